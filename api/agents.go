@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/scheduler"
+)
+
+// GET /agents
+func listAgents(ctx *mux.Context) {
+	agents, err := sched.Agents()
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, agents)
+}
+
+// POST /agents/:id/drain?force=true
+func drainAgent(ctx *mux.Context) {
+	var (
+		id    = ctx.Ps["id"]
+		force = ctx.Qs["force"] == "true"
+	)
+
+	agentOp(ctx, sched.Drain(id, force))
+}
+
+// POST /agents/:id/blacklist
+func blacklistAgent(ctx *mux.Context) {
+	agentOp(ctx, sched.Blacklist(ctx.Ps["id"]))
+}
+
+// POST /agents/:id/activate
+func activateAgent(ctx *mux.Context) {
+	agentOp(ctx, sched.Activate(ctx.Ps["id"]))
+}
+
+func agentOp(ctx *mux.Context, err error) {
+	switch err {
+	case nil:
+		ctx.Status(202)
+	case scheduler.ErrNoSuchAgent:
+		ctx.NotFound(err)
+	default:
+		ctx.Error(500, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bbklab/swan-ng/api/mux"
 	"github.com/bbklab/swan-ng/mesos"
+	"github.com/bbklab/swan-ng/scheduler"
 	"github.com/bbklab/swan-ng/store"
 	"github.com/bbklab/swan-ng/types"
 )
@@ -18,8 +19,9 @@ const (
 
 // package scope instances
 var (
-	cfg      = new(types.MgrConfig) // manager configs
-	mesosCli = new(mesos.Client)    // mesos scheduler client
+	cfg      = new(types.MgrConfig)     // manager configs
+	mesosCli = new(mesos.Client)        // mesos scheduler client
+	sched    = new(scheduler.Scheduler) // swan framework scheduler
 )
 
 // Serve initilize & startup http api services
@@ -49,6 +51,12 @@ func Serve(mc *types.MgrConfig) (err error) {
 		return fmt.Errorf("initialize db store error: [%v]", err)
 	}
 
+	// setup & startup the framework scheduler
+	sched = scheduler.New(cfg, mesosCli, eventMgr.broadCast)
+	if err := sched.Start(); err != nil {
+		return fmt.Errorf("startup scheduler error: [%v]", err)
+	}
+
 	// setup http routes & serving
 	mux := mux.New()
 	setupRouters(mux)
@@ -67,12 +75,52 @@ func setupRouters(m *mux.Mux) {
 	m.Get("/events", events)
 	m.Get("/stats", stats)
 	m.Get("/version", showVersion)
+	m.Get("/queue", listQueue)
+
+	// agents
+	m.Get("/agents", listAgents)
+	m.Post("/agents/:id/drain", drainAgent)
+	m.Post("/agents/:id/blacklist", blacklistAgent)
+	m.Post("/agents/:id/activate", activateAgent)
 
 	// apps
 	m.Get("/apps", listApps)
 	m.Get("/apps/:id", getApp)
-	//m.Delete("/apps/:id", delApp)
-	//m.Patch("/apps/:id/scale", scaleApp)
+	m.Post("/apps", createApp)
+	m.Post("/apps/dry-run", dryRunApp)
+	m.Put("/apps/:id", updateApp)
+	m.Get("/apps/:id/versions", listVersions)
+	m.Get("/apps/:id/versions/:vid", getVersion)
+	m.Get("/apps/:id/versions/:a/diff/:b", diffVersions)
+	m.Get("/apps/:id/diff", diffProposed)
+	m.Post("/apps/:id/rollback", rollbackApp)
+	m.Post("/apps/:id/suspend", suspendApp)
+	m.Post("/apps/:id/resume", resumeApp)
+	m.Post("/apps/:id/restart", restartApp)
+	m.Get("/apps/:id/tasks", listTasks)
+	m.Get("/apps/:id/tasks/:tid", getTask)
+	m.Get("/apps/:id/tasks/:tid/histories", listTaskHistories)
+	m.Delete("/apps/:id/tasks/:tid", killTask)
+	m.Post("/apps/:id/canary/promote", promoteCanary)
+	m.Post("/apps/:id/canary/abort", abortCanary)
+	m.Post("/apps/:id/bluegreen/switch-back", switchBack)
+	m.Delete("/apps/:id", delApp)
+	m.Patch("/apps/scale", scaleApps)
+	m.Patch("/apps/:id/scale", scaleApp)
+
+	// compose instances
+	m.Get("/compose", listInstances)
+	m.Get("/compose/:id", getInstance)
+	m.Post("/compose", createInstance)
+	m.Post("/compose/parse", parseInstance)
+	m.Delete("/compose/:id", delInstance)
+
+	// cron jobs
+	m.Get("/cronjobs", listCronJobs)
+	m.Get("/cronjobs/:id", getCronJob)
+	m.Post("/cronjobs", createCronJob)
+	m.Post("/cronjobs/:id/run", runCronJob)
+	m.Delete("/cronjobs/:id", delCronJob)
 }
 
 // GET /
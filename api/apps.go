@@ -1,31 +1,305 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
 	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/scheduler"
 	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	defaultKillTimeout = time.Minute // default timeout of waiting for the killed tasks gone
 )
 
-// GET /apps
+// GET /apps?embed=tasks,versions&labels=k=v&sort=name&limit=100&next=token
+// see appQuery for all of the query params, the url of the next page is
+// responded in the `Link` header.
 func listApps(ctx *mux.Context) {
+	q, err := parseAppQuery(ctx.Qs)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
 	apps, err := store.DB().ListApps()
 	if err != nil {
 		ctx.Error(500, err)
 		return
 	}
 
-	ctx.JSON(200, apps)
+	apps, next := q.apply(apps)
+	if next != nil {
+		ctx.Res.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextLink(ctx.Req.URL, next)))
+		ctx.Res.Header().Set("X-Next-Token", encodeCursor(next))
+	}
+
+	embeds := parseEmbeds(ctx.Qs["embed"])
+
+	ret := make([]*types.AppWrapper, 0, len(apps))
+	for _, app := range apps {
+		w, err := wrapApp(app, embeds)
+		if err != nil {
+			ctx.Error(500, err)
+			return
+		}
+		ret = append(ret, w)
+	}
+
+	ctx.JSON(200, ret)
 }
 
-// GET /apps/:id
+// GET /apps/:id?embed=tasks,versions
 func getApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	w, err := wrapApp(app, parseEmbeds(ctx.Qs["embed"]))
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, w)
+}
+
+// wrapApp wrap the app with the runtime fields, the tasks and the version ids
+// are only included if embedded.
+func wrapApp(app *types.App, embeds map[string]bool) (*types.AppWrapper, error) {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := types.NewAppWrapper(app, tasks)
+	if embeds["tasks"] {
+		w.Tasks = tasks
+	}
+
+	if embeds["versions"] {
+		vers, err := store.DB().ListVersions(app.ID)
+		if err != nil {
+			return nil, err
+		}
+		w.Versions = make([]string, 0, len(vers))
+		for _, v := range vers {
+			w.Versions = append(w.Versions, v.ID)
+		}
+	}
+
+	return w, nil
+}
+
+// parseEmbeds parse the comma separated embedded fields
+func parseEmbeds(expr string) map[string]bool {
+	ret := make(map[string]bool)
+	for _, f := range strings.Split(expr, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ret[f] = true
+		}
+	}
+	return ret
+}
+
+// DELETE /apps/:id?force=true&timeout=2m
+func delApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	force := ctx.Qs["force"] == "true"
+	if app.State == types.AppDeleting && !force {
+		ctx.Conflict("app is being deleted, retry with force")
+		return
+	}
+
+	timeout, ok := waitTimeout(ctx, app)
+	if !ok {
+		return
+	}
+
+	if err := sched.DeleteApp(app, force, timeout); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// waitTimeout return the timeout of waiting for the app's killed tasks gone from
+// query param `timeout`, it responses the error and returns false if invalid.
+func waitTimeout(ctx *mux.Context, app *types.App) (time.Duration, bool) {
+	return parseTimeout(ctx, killTimeout(app))
+}
+
+// parseTimeout return the duration of query param `timeout`, or the default
+// if not specified. it responses the error and returns false if invalid.
+func parseTimeout(ctx *mux.Context, def time.Duration) (time.Duration, bool) {
+	v := ctx.Qs["timeout"]
+	if v == "" {
+		return def, true
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		ctx.BadRequest(fmt.Sprintf("invalid timeout: %v", err))
+		return 0, false
+	}
+	return d, true
+}
+
+// loadApp load the app specified by path param `id`,
+// it responses the error and returns nil if failed.
+func loadApp(ctx *mux.Context) *types.App {
 	id := ctx.Ps["id"]
 
 	app, err := store.DB().GetApp(id)
 	if err != nil {
+		if store.IsNotFound(err) {
+			ctx.NotFound(fmt.Sprintf("no such app: %s", id))
+			return nil
+		}
+		ctx.Error(500, err)
+		return nil
+	}
+
+	return app
+}
+
+// POST /apps?dryRun=true&var.NAME=value
+func createApp(ctx *mux.Context) {
+	var ver types.AppVersion
+	if !decodeBody(ctx, &ver) {
+		return
+	}
+
+	if err := ver.Valid(); err != nil {
+		invalid(ctx, err)
+		return
+	}
+
+	app := scheduler.NewApp(mesosCli.Cluster(), &ver)
+
+	if _, err := store.DB().GetApp(app.ID); err == nil {
+		ctx.Conflict(fmt.Sprintf("app %s already exists", app.ID))
+		return
+	} else if !store.IsNotFound(err) {
+		ctx.Error(500, err)
+		return
+	}
+
+	// checked the same as the real creation, with the derived instances
+	if ctx.Qs["dryRun"] == "true" {
+		ctx.JSON(200, sched.DryRun(app.Version))
+		return
+	}
+
+	if err := sched.CreateApp(app); err != nil {
+		if store.IsExists(err) {
+			ctx.Conflict(fmt.Sprintf("app %s already exists", app.ID))
+			return
+		}
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(201, map[string]string{
+		"id": app.ID,
+	})
+}
+
+// POST /apps/dry-run?var.NAME=value
+func dryRunApp(ctx *mux.Context) {
+	var ver types.AppVersion
+	if !decodeBody(ctx, &ver) {
+		return
+	}
+
+	if err := ver.Valid(); err != nil {
+		invalid(ctx, err)
+		return
+	}
+
+	// the instances derived the same as the real creation, eg: of the jobs
+	app := scheduler.NewApp(mesosCli.Cluster(), &ver)
+	if app.Version.Instances <= 0 && !ver.IsDaemon() {
+		ctx.BadRequest("instances should be positive")
+		return
+	}
+
+	ctx.JSON(200, sched.DryRun(app.Version))
+}
+
+// queryVars return the variables from query params `var.NAME=value`
+func queryVars(qs mux.Params) map[string]string {
+	ret := make(map[string]string)
+	for k, v := range qs {
+		if strings.HasPrefix(k, "var.") {
+			ret[strings.TrimPrefix(k, "var.")] = v
+		}
+	}
+	return ret
+}
+
+// decodeBody decode the request body into v with the query variables substituted
+// into the raw json if any, so the numeric or bool settings could be variables too.
+// it responses the error and returns false if failed.
+func decodeBody(ctx *mux.Context, v interface{}) bool {
+	vars := queryVars(ctx.Qs)
+	if len(vars) == 0 {
+		if err := json.NewDecoder(ctx.Req.Body).Decode(v); err != nil {
+			ctx.BadRequest(err)
+			return false
+		}
+		return true
+	}
+
+	data, err := ioutil.ReadAll(ctx.Req.Body)
+	if err != nil {
+		ctx.BadRequest(err)
+		return false
+	}
+	if err := types.DecodeInterpolated(data, vars, v); err != nil {
+		ctx.BadRequest(err)
+		return false
+	}
+	return true
+}
+
+// opFailed response the error of the operation on the app,
+// the operations conflict with the app's state or ongoing update are 409.
+func opFailed(ctx *mux.Context, err error) {
+	if _, ok := err.(*types.StateError); ok {
+		ctx.Conflict(err.Error())
+		return
+	}
+
+	switch err {
+	case scheduler.ErrUpdating, scheduler.ErrNoCanary, scheduler.ErrCanaryNotReady, scheduler.ErrNoBlueGreen, scheduler.ErrJobApp:
+		ctx.Conflict(err.Error())
+	default:
 		ctx.Error(500, err)
+	}
+}
+
+// invalid response the validation errors, with the field level details if any
+func invalid(ctx *mux.Context, err error) {
+	errs, ok := err.(types.ValidationErrors)
+	if !ok {
+		ctx.BadRequest(err)
 		return
 	}
 
-	// TODO wrap app within types.AppWrapper
-	ctx.JSON(200, app)
+	ctx.JSON(400, map[string]interface{}{
+		"error":  err.Error(),
+		"fields": errs,
+	})
 }
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+)
+
+// POST /apps/:id/canary/promote
+func promoteCanary(ctx *mux.Context) {
+	updateOp(ctx, sched.PromoteCanary)
+}
+
+// POST /apps/:id/canary/abort
+func abortCanary(ctx *mux.Context) {
+	updateOp(ctx, sched.AbortCanary)
+}
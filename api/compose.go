@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/compose"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// POST /compose?overlay=prod&var.NAME=value
+// the query overlay and variables take precedence over the ones in the body.
+func createInstance(ctx *mux.Context) {
+	var ins types.Instance
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&ins); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if o := ctx.Qs["overlay"]; o != "" {
+		ins.Overlay = o
+	}
+	for k, v := range queryVars(ctx.Qs) {
+		if ins.Variables == nil {
+			ins.Variables = make(map[string]string)
+		}
+		ins.Variables[k] = v
+	}
+	if err := ins.Render(); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	ins.Expand()
+	if err := ins.Valid(); err != nil {
+		invalid(ctx, err)
+		return
+	}
+
+	ins.ClusterID = mesosCli.Cluster()
+	ins.ID = types.ObjectID(ins.Name, ins.RunAs, ins.ClusterID)
+
+	if _, err := store.DB().GetInstance(ins.ID); err == nil {
+		ctx.Conflict(fmt.Sprintf("compose instance %s already exists", ins.ID))
+		return
+	} else if !store.IsNotFound(err) {
+		ctx.Error(500, err)
+		return
+	}
+
+	for _, svc := range ins.Services {
+		id := types.ObjectID(svc.Version.AppName, svc.Version.RunAs, ins.ClusterID)
+		if _, err := store.DB().GetApp(id); err == nil {
+			ctx.Conflict(fmt.Sprintf("app %s of service %s already exists", id, svc.Name))
+			return
+		} else if !store.IsNotFound(err) {
+			ctx.Error(500, err)
+			return
+		}
+	}
+
+	if err := sched.CreateInstance(&ins); err != nil {
+		if store.IsExists(err) {
+			ctx.Conflict(fmt.Sprintf("compose instance %s already exists", ins.ID))
+			return
+		}
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(201, map[string]string{
+		"id": ins.ID,
+	})
+}
+
+// POST /compose/parse?name=xxx&runAs=xxx&var.NAME=value
+// convert the docker-compose yaml into the compose instance to be created,
+// with the warnings of the unsupported keys. the variables if any are
+// interpolated into the yaml before parsed.
+func parseInstance(ctx *mux.Context) {
+	data, err := ioutil.ReadAll(ctx.Req.Body)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	if vars := queryVars(ctx.Qs); len(vars) > 0 {
+		text, err := types.Interpolate(string(data), vars)
+		if err != nil {
+			ctx.BadRequest(err)
+			return
+		}
+		data = []byte(text)
+	}
+
+	ins, warnings, err := compose.Parse(data)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	ins.Name = ctx.Qs["name"]
+	ins.RunAs = ctx.Qs["runAs"]
+	ins.Expand()
+
+	ctx.JSON(200, map[string]interface{}{
+		"instance": ins,
+		"warnings": warnings,
+	})
+}
+
+// GET /compose
+func listInstances(ctx *mux.Context) {
+	inss, err := store.DB().ListInstances()
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ret := make([]*types.InstanceWrapper, 0, len(inss))
+	for _, ins := range inss {
+		w, err := wrapInstance(ins)
+		if err != nil {
+			ctx.Error(500, err)
+			return
+		}
+		ret = append(ret, w)
+	}
+
+	ctx.JSON(200, ret)
+}
+
+// GET /compose/:id
+func getInstance(ctx *mux.Context) {
+	ins := loadInstance(ctx)
+	if ins == nil {
+		return
+	}
+
+	w, err := wrapInstance(ins)
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, w)
+}
+
+// DELETE /compose/:id?force=true&timeout=2m
+func delInstance(ctx *mux.Context) {
+	ins := loadInstance(ctx)
+	if ins == nil {
+		return
+	}
+
+	if ins.Status == types.InstanceDeleting && ctx.Qs["force"] != "true" {
+		ctx.Conflict("compose instance is being deleted, retry with force")
+		return
+	}
+
+	// the longest grace period of the member apps by default
+	def := defaultKillTimeout
+	for _, svc := range ins.Services {
+		if p := svc.Version.KillPolicy; p != nil {
+			if d := defaultKillTimeout + time.Duration(p.Duration)*time.Second; d > def {
+				def = d
+			}
+		}
+	}
+
+	timeout, ok := parseTimeout(ctx, def)
+	if !ok {
+		return
+	}
+
+	if err := sched.DeleteInstance(ins, ctx.Qs["force"] == "true", timeout); err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// wrapInstance wrap the compose instance with the member apps' states
+func wrapInstance(ins *types.Instance) (*types.InstanceWrapper, error) {
+	w := &types.InstanceWrapper{
+		Instance: ins,
+		Apps:     make([]*types.ServiceStatus, 0, len(ins.Services)),
+	}
+
+	states := make([]string, 0, len(ins.Services))
+	for _, svc := range ins.Services {
+		st := &types.ServiceStatus{Service: svc.Name, AppID: svc.AppID, State: "missing"}
+		w.Apps = append(w.Apps, st)
+
+		app, err := store.DB().GetApp(svc.AppID)
+		if err != nil && !store.IsNotFound(err) {
+			return nil, err
+		}
+		if app != nil {
+			aw, err := wrapApp(app, nil)
+			if err != nil {
+				return nil, err
+			}
+			st.State = app.State
+			st.Instances = app.Version.Instances
+			st.RunningInstances = aw.RunningInstances
+			st.ErrMsg = app.ErrMsg
+		}
+		if ins.Status == types.InstanceCreating && app == nil {
+			continue // not created yet
+		}
+		states = append(states, st.State)
+	}
+
+	w.State = types.AggregateState(states)
+	if ins.Status == types.InstanceDeleting {
+		w.State = types.InstanceDeleting
+	}
+	return w, nil
+}
+
+// loadInstance load the compose instance specified by path param `id`,
+// it responses the error and returns nil if failed.
+func loadInstance(ctx *mux.Context) *types.Instance {
+	id := ctx.Ps["id"]
+
+	ins, err := store.DB().GetInstance(id)
+	if err != nil {
+		if store.IsNotFound(err) {
+			ctx.NotFound(fmt.Sprintf("no such compose instance: %s", id))
+			return nil
+		}
+		ctx.Error(500, err)
+		return nil
+	}
+
+	return ins
+}
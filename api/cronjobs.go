@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/scheduler"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// POST /cronjobs?var.NAME=value
+func createCronJob(ctx *mux.Context) {
+	var cj types.CronJob
+	if !decodeBody(ctx, &cj) {
+		return
+	}
+
+	// the template is validated as the app of a run
+	if cj.Template != nil {
+		cj.Template.AppName = cj.RunName(time.Now(), true)
+		cj.Template.RunAs = cj.RunAs
+	}
+
+	if err := cj.Valid(); err != nil {
+		invalid(ctx, err)
+		return
+	}
+
+	cj.ClusterID = mesosCli.Cluster()
+	cj.ID = types.ObjectID(cj.Name, cj.RunAs, cj.ClusterID)
+	cj.LastScheduleTime, cj.NextScheduleTime, cj.Runs = 0, 0, nil
+
+	if _, err := store.DB().GetCronJob(cj.ID); err == nil {
+		ctx.Conflict(fmt.Sprintf("cron job %s already exists", cj.ID))
+		return
+	} else if !store.IsNotFound(err) {
+		ctx.Error(500, err)
+		return
+	}
+
+	if err := sched.CreateCronJob(&cj); err != nil {
+		if store.IsExists(err) {
+			ctx.Conflict(fmt.Sprintf("cron job %s already exists", cj.ID))
+			return
+		}
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(201, map[string]string{
+		"id": cj.ID,
+	})
+}
+
+// GET /cronjobs
+func listCronJobs(ctx *mux.Context) {
+	cjs, err := store.DB().ListCronJobs()
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	for _, cj := range cjs {
+		fillRuns(cj)
+	}
+
+	ctx.JSON(200, cjs)
+}
+
+// GET /cronjobs/:id
+func getCronJob(ctx *mux.Context) {
+	cj := loadCronJob(ctx)
+	if cj == nil {
+		return
+	}
+
+	fillRuns(cj)
+	ctx.JSON(200, cj)
+}
+
+// DELETE /cronjobs/:id?timeout=2m
+// the run apps of the cron job are deleted as well.
+func delCronJob(ctx *mux.Context) {
+	cj := loadCronJob(ctx)
+	if cj == nil {
+		return
+	}
+
+	timeout, ok := parseTimeout(ctx, defaultKillTimeout)
+	if !ok {
+		return
+	}
+
+	if err := sched.DeleteCronJob(cj, timeout); err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// POST /cronjobs/:id/run
+// start a run of the cron job immediately, the concurrency policy still applies.
+func runCronJob(ctx *mux.Context) {
+	cj := loadCronJob(ctx)
+	if cj == nil {
+		return
+	}
+
+	run, err := sched.RunCronJob(cj.ID)
+	if err != nil {
+		if err == scheduler.ErrCronJobRunning {
+			ctx.Conflict(fmt.Sprintf("%v: %s", err, run.Message))
+			return
+		}
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(202, run)
+}
+
+// fillRuns fill the job status of the cron job's runs from their apps
+func fillRuns(cj *types.CronJob) {
+	for _, run := range cj.Runs {
+		if run.AppID == "" {
+			continue
+		}
+		if app, err := store.DB().GetApp(run.AppID); err == nil {
+			run.Job = app.Job
+		}
+	}
+}
+
+// loadCronJob load the cron job specified by path param `id`,
+// it responses the error and returns nil if failed.
+func loadCronJob(ctx *mux.Context) *types.CronJob {
+	id := ctx.Ps["id"]
+
+	cj, err := store.DB().GetCronJob(id)
+	if err != nil {
+		if store.IsNotFound(err) {
+			ctx.NotFound(fmt.Sprintf("no such cron job: %s", id))
+			return nil
+		}
+		ctx.Error(500, err)
+		return nil
+	}
+
+	return cj
+}
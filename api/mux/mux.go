@@ -203,12 +203,13 @@ func (r *Route) init(pattern string) {
 		fields = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
 	)
 
+	// path params could be any chars except `/`, eg: mesos agent id, task id
 	newRegStr := reg.ReplaceAllStringFunc(pattern, func(s string) string {
 		keys = append(keys, s[2:]) // trim heading 2 chars /:
-		return fmt.Sprintf("/(?P<%s>[a-zA-Z0-9_]+)", s[2:])
+		return fmt.Sprintf("/(?P<%s>[^/]+)", s[2:])
 	})
 
-	r.reg = regexp.MustCompile(newRegStr)
+	r.reg = regexp.MustCompile("^" + newRegStr + "$")
 	r.paramKeys = keys
 	r.numField = len(fields)
 }
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	maxPageSize = 1000 // max nb of apps per page
+)
+
+// appQuery represents the filters, the order and the page of app listing
+//
+//	labels=k=v,k2!=v2  label selector
+//	runAs=xxx          apps run as the user
+//	state=normal       apps in the state
+//	cluster=xxx        apps of the cluster
+//	prefix=xxx         apps whose name starts with
+//	sort=name          one of [name createdAt updatedAt], default name
+//	limit=100          page size, all of the apps if not specified
+//	next=token         the cursor returned by the previous page
+type appQuery struct {
+	selector types.Selector
+	runAs    string
+	state    string
+	cluster  string
+	prefix   string
+	sortBy   string
+	limit    int
+	after    *appCursor
+}
+
+// appCursor is the position after which the next page starts
+type appCursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+// parseAppQuery parse the app listing query params
+func parseAppQuery(qs mux.Params) (*appQuery, error) {
+	q := &appQuery{
+		runAs:   qs["runAs"],
+		state:   qs["state"],
+		cluster: qs["cluster"],
+		prefix:  qs["prefix"],
+		sortBy:  qs["sort"],
+	}
+
+	if expr := qs["labels"]; expr != "" {
+		selector, err := types.ParseSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		q.selector = selector
+	}
+
+	switch q.sortBy {
+	case "":
+		q.sortBy = "name"
+	case "name", "createdAt", "updatedAt":
+	default:
+		return nil, fmt.Errorf("unsupported sort %q, should be one of [name createdAt updatedAt]", q.sortBy)
+	}
+
+	if v := qs["limit"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxPageSize {
+			return nil, fmt.Errorf("limit should be in range [1, %d]", maxPageSize)
+		}
+		q.limit = n
+	}
+
+	if token := qs["next"]; token != "" {
+		c, err := decodeCursor(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid next token")
+		}
+		q.after = c
+	}
+
+	return q, nil
+}
+
+// match check if the app matches all of the filters
+func (q *appQuery) match(app *types.App) bool {
+	if q.state != "" && app.State != q.state {
+		return false
+	}
+	if q.cluster != "" && app.ClusterID != q.cluster {
+		return false
+	}
+	if q.prefix != "" && !strings.HasPrefix(app.Name, q.prefix) {
+		return false
+	}
+
+	ver := app.Version
+	if ver == nil {
+		return q.runAs == "" && len(q.selector) == 0
+	}
+	if q.runAs != "" && ver.RunAs != q.runAs {
+		return false
+	}
+	return q.selector.Matches(ver.Labels)
+}
+
+// key return the sort key of the app, the timestamps are zero padded
+// to be compared as strings.
+func (q *appQuery) key(app *types.App) string {
+	switch q.sortBy {
+	case "createdAt":
+		return fmt.Sprintf("%020d", app.CreatedAt)
+	case "updatedAt":
+		return fmt.Sprintf("%020d", app.UpdatedAt)
+	default:
+		return app.Name
+	}
+}
+
+// apply filter, sort and paginate the apps, it returns the apps in the page
+// and the cursor of the next page, nil if it's the last page.
+func (q *appQuery) apply(apps []*types.App) ([]*types.App, *appCursor) {
+	items := make(appItems, 0, len(apps))
+	for _, app := range apps {
+		if q.match(app) {
+			items = append(items, &appItem{key: q.key(app), app: app})
+		}
+	}
+	sort.Sort(items)
+
+	if c := q.after; c != nil {
+		i := sort.Search(len(items), func(i int) bool {
+			return items[i].key > c.Key || (items[i].key == c.Key && items[i].app.ID > c.ID)
+		})
+		items = items[i:]
+	}
+
+	var next *appCursor
+	if q.limit > 0 && len(items) > q.limit {
+		items = items[:q.limit]
+		last := items[len(items)-1]
+		next = &appCursor{Key: last.key, ID: last.app.ID}
+	}
+
+	ret := make([]*types.App, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.app)
+	}
+	return ret, next
+}
+
+func encodeCursor(c *appCursor) string {
+	bs, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+func decodeCursor(token string) (*appCursor, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var c appCursor
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// nextLink return the url of the next page with the same query
+func nextLink(u *url.URL, c *appCursor) string {
+	qs := u.Query()
+	qs.Set("next", encodeCursor(c))
+
+	next := *u
+	next.RawQuery = qs.Encode()
+	return next.RequestURI()
+}
+
+type appItem struct {
+	key string
+	app *types.App
+}
+
+// appItems sort apps by the sort key, then by id
+type appItems []*appItem
+
+func (s appItems) Len() int      { return len(s) }
+func (s appItems) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s appItems) Less(i, j int) bool {
+	if s[i].key != s[j].key {
+		return s[i].key < s[j].key
+	}
+	return s[i].app.ID < s[j].app.ID
+}
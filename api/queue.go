@@ -0,0 +1,10 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+)
+
+// GET /queue
+func listQueue(ctx *mux.Context) {
+	ctx.JSON(200, sched.Queue())
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+)
+
+// POST /apps/:id/restart?agent=agentID&unhealthy=true
+func restartApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	n, err := sched.RestartApp(app, ctx.Qs["agent"], ctx.Qs["unhealthy"] == "true")
+	if err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	code := 202
+	if n == 0 {
+		code = 200 // nothing to restart
+	}
+	ctx.JSON(code, map[string]int{
+		"tasks": n,
+	})
+}
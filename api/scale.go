@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/scheduler"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// scaleBody is the request body of scaling,
+// exactly one of the absolute `instances` or the relative `delta` is required.
+type scaleBody struct {
+	Instances *int   `json:"instances,omitempty"`
+	Delta     *int   `json:"delta,omitempty"`
+	Policy    string `json:"policy,omitempty"` // victim selection policy on scaling down
+}
+
+func (b *scaleBody) valid() error {
+	if (b.Instances == nil) == (b.Delta == nil) {
+		return fmt.Errorf("exactly one of instances or delta required")
+	}
+	if !scheduler.ValidVictimPolicy(b.Policy) {
+		return fmt.Errorf("unsupported policy %q, should be one of [%s %s %s]", b.Policy,
+			scheduler.VictimNewest, scheduler.VictimUnhealthy, scheduler.VictimCrowded)
+	}
+	return nil
+}
+
+// target calculate the desired nb of instances of the app
+func (b *scaleBody) target(app *types.App) (int, error) {
+	n := int(app.Version.Instances)
+	if b.Instances != nil {
+		n = *b.Instances
+	} else {
+		n += *b.Delta
+	}
+
+	if app.Version.IsDaemon() {
+		return 0, fmt.Errorf("daemon mode app could not be scaled, the instances are derived from the matching agents")
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("instances should not be negative, got %d", n)
+	}
+	if app.Version.Mode == "fixed" && n != int(app.Version.Instances) {
+		return 0, fmt.Errorf("fixed mode app could not be scaled")
+	}
+	return n, nil
+}
+
+// scaleResult represents the scaling result of single app
+type scaleResult struct {
+	ID    string `json:"id"`
+	From  int32  `json:"from"`
+	To    int    `json:"to"`
+	Error string `json:"error,omitempty"`
+}
+
+// PATCH /apps/:id/scale
+func scaleApp(ctx *mux.Context) {
+	var body scaleBody
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&body); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+	if err := body.valid(); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	n, err := body.target(app)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	ret := &scaleResult{ID: app.ID, From: app.Version.Instances, To: n}
+	if err := sched.ScaleApp(app, n, body.Policy, killTimeout(app)); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.JSON(202, ret)
+}
+
+// PATCH /apps/scale?labels=k=v,k2!=v2
+func scaleApps(ctx *mux.Context) {
+	var body scaleBody
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&body); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+	if err := body.valid(); err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	expr := ctx.Qs["labels"]
+	if expr == "" {
+		ctx.BadRequest("label selector `labels` required")
+		return
+	}
+	selector, err := types.ParseSelector(expr)
+	if err != nil {
+		ctx.BadRequest(err)
+		return
+	}
+
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ret := make([]*scaleResult, 0)
+	for _, app := range apps {
+		if !selector.Matches(app.Version.Labels) {
+			continue
+		}
+
+		r := &scaleResult{ID: app.ID, From: app.Version.Instances, To: int(app.Version.Instances)}
+		ret = append(ret, r)
+
+		n, err := body.target(app)
+		if err != nil {
+			r.Error = err.Error()
+			continue
+		}
+		r.To = n
+
+		if err := sched.ScaleApp(app, n, body.Policy, killTimeout(app)); err != nil {
+			r.Error = err.Error()
+		}
+	}
+
+	ctx.JSON(202, ret)
+}
+
+// killTimeout return the timeout of waiting for the app's killed tasks gone:
+// the grace period plus a while
+func killTimeout(app *types.App) time.Duration {
+	timeout := defaultKillTimeout
+	if p := app.Version.KillPolicy; p != nil {
+		timeout += time.Duration(p.Duration) * time.Second
+	}
+	return timeout
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+)
+
+// POST /apps/:id/suspend?timeout=2m
+func suspendApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	timeout, ok := waitTimeout(ctx, app)
+	if !ok {
+		return
+	}
+
+	if err := sched.SuspendApp(app, timeout); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// POST /apps/:id/resume
+func resumeApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	if err := sched.ResumeApp(app); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
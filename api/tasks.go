@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// GET /apps/:id/tasks
+func listTasks(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, tasks)
+}
+
+// GET /apps/:id/tasks/:tid
+func getTask(ctx *mux.Context) {
+	task := loadTask(ctx)
+	if task == nil {
+		return
+	}
+
+	ctx.JSON(200, task)
+}
+
+// GET /apps/:id/tasks/:tid/histories
+func listTaskHistories(ctx *mux.Context) {
+	task := loadTask(ctx)
+	if task == nil {
+		return
+	}
+
+	hs, err := store.DB().GetTaskHistories(task.AppID, task.ID)
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, hs)
+}
+
+// DELETE /apps/:id/tasks/:tid?scale=true&timeout=2m
+// the killed task is replaced by a new one, or with scale the app is scaled down by one.
+func killTask(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	task := loadTask(ctx)
+	if task == nil {
+		return
+	}
+
+	if app.State == types.AppDeleting {
+		ctx.Conflict("app is being deleted")
+		return
+	}
+
+	if ctx.Qs["scale"] != "true" {
+		if err := sched.KillTask(task, app.Version.KillPolicy, true); err != nil {
+			ctx.Error(500, err)
+			return
+		}
+		ctx.Status(202)
+		return
+	}
+
+	if app.Version.Mode == "fixed" || app.Version.IsDaemon() {
+		ctx.BadRequest(fmt.Sprintf("%s mode app could not be scaled", app.Version.Mode))
+		return
+	}
+
+	timeout, ok := waitTimeout(ctx, app)
+	if !ok {
+		return
+	}
+
+	if err := sched.ScaleDownTask(app, task, timeout); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// loadTask load the task specified by path params `id` and `tid`,
+// it responses the error and returns nil if failed.
+func loadTask(ctx *mux.Context) *types.Task {
+	var (
+		id  = ctx.Ps["id"]
+		tid = ctx.Ps["tid"]
+	)
+
+	task, err := store.DB().GetTask(id, tid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			ctx.NotFound(fmt.Sprintf("no such task: %s", tid))
+			return nil
+		}
+		ctx.Error(500, err)
+		return nil
+	}
+
+	return task
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/scheduler"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// PUT /apps/:id?dryRun=true&var.NAME=value
+func updateApp(ctx *mux.Context) {
+	var ver types.AppVersion
+	if !decodeBody(ctx, &ver) {
+		return
+	}
+
+	if err := ver.Valid(); err != nil {
+		invalid(ctx, err)
+		return
+	}
+
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	if ver.AppName != app.Version.AppName || ver.RunAs != app.Version.RunAs {
+		ctx.BadRequest("appName and runAs could not be changed")
+		return
+	}
+
+	if ver.IsDaemon() != app.Version.IsDaemon() {
+		ctx.BadRequest("mode could not be changed from or to daemon")
+		return
+	}
+
+	if ctx.Qs["dryRun"] == "true" {
+		ctx.JSON(200, sched.DryRun(&ver))
+		return
+	}
+
+	scheduler.StampVersion(app.ID, &ver)
+
+	if err := sched.UpdateApp(app, &ver); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// POST /apps/:id/bluegreen/switch-back
+func switchBack(ctx *mux.Context) {
+	updateOp(ctx, sched.SwitchBack)
+}
+
+// updateOp apply the operation on the app's ongoing update
+func updateOp(ctx *mux.Context, op func(string) error) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	if err := op(app.ID); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/bbklab/swan-ng/api/mux"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// GET /apps/:id/versions
+func listVersions(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	vers, err := store.DB().ListVersions(app.ID)
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, vers)
+}
+
+// GET /apps/:id/versions/:vid
+func getVersion(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	ver := loadVersion(ctx, app.ID, ctx.Ps["vid"])
+	if ver == nil {
+		return
+	}
+
+	ctx.JSON(200, ver)
+}
+
+// POST /apps/:id/rollback?version=vid
+// rolling update the app back to the specified version, or the previous one if not specified.
+// the previous version is the newest one older than the current, the newer ones are the
+// failed or aborted updates.
+func rollbackApp(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	var ver *types.AppVersion
+	if vid := ctx.Qs["version"]; vid != "" {
+		if ver = loadVersion(ctx, app.ID, vid); ver == nil {
+			return
+		}
+	} else {
+		vers, err := store.DB().ListVersions(app.ID)
+		if err != nil {
+			ctx.Error(500, err)
+			return
+		}
+		for _, v := range vers {
+			if v.CreatedAt < app.Version.CreatedAt {
+				ver = v
+				break
+			}
+		}
+		if ver == nil {
+			ctx.Conflict("no previous version to rollback to")
+			return
+		}
+	}
+
+	if ver.ID == app.Version.ID {
+		ctx.Conflict(fmt.Sprintf("version %s is the current version", ver.ID))
+		return
+	}
+
+	if err := sched.RollbackApp(app, ver); err != nil {
+		opFailed(ctx, err)
+		return
+	}
+
+	ctx.Status(202)
+}
+
+// loadVersion load the app's specified version,
+// it responses the error and returns nil if failed.
+func loadVersion(ctx *mux.Context, appID, vid string) *types.AppVersion {
+	ver, err := store.DB().GetVersion(appID, vid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			ctx.NotFound(fmt.Sprintf("no such version: %s", vid))
+			return nil
+		}
+		ctx.Error(500, err)
+		return nil
+	}
+
+	return ver
+}
+
+// GET /apps/:id/versions/:a/diff/:b
+// the special version ids `current` and `proposed` refer to
+// the app's current version and proposed version.
+func diffVersions(ctx *mux.Context) {
+	app := loadApp(ctx)
+	if app == nil {
+		return
+	}
+
+	a := resolveVersion(ctx, app, ctx.Ps["a"])
+	if a == nil {
+		return
+	}
+	b := resolveVersion(ctx, app, ctx.Ps["b"])
+	if b == nil {
+		return
+	}
+
+	changes, err := types.DiffVersions(a, b)
+	if err != nil {
+		ctx.Error(500, err)
+		return
+	}
+
+	ctx.JSON(200, map[string]interface{}{
+		"from":    a.ID,
+		"to":      b.ID,
+		"changes": changes,
+	})
+}
+
+// GET /apps/:id/diff
+// diff between the app's current version and proposed version.
+func diffProposed(ctx *mux.Context) {
+	ctx.Ps["a"], ctx.Ps["b"] = "current", "proposed"
+	diffVersions(ctx)
+}
+
+func resolveVersion(ctx *mux.Context, app *types.App, vid string) *types.AppVersion {
+	switch vid {
+	case "current":
+		return app.Version
+	case "proposed":
+		if app.ProposedVersion == nil {
+			ctx.NotFound("app has no proposed version")
+		}
+		return app.ProposedVersion
+	}
+	return loadVersion(ctx, app.ID, vid)
+}
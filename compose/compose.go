@@ -0,0 +1,404 @@
+// Package compose converts docker-compose files into swan compose instances.
+//
+// only a subset of the compose file v2/v3 format is supported:
+//
+//	services.<name>.image
+//	services.<name>.command
+//	services.<name>.environment
+//	services.<name>.ports
+//	services.<name>.volumes         (host path bind mounts only)
+//	services.<name>.labels
+//	services.<name>.depends_on
+//	services.<name>.deploy.replicas
+//	services.<name>.deploy.mode     (global as daemon mode)
+//	services.<name>.deploy.resources.limits.cpus|memory
+//
+// the other keys are ignored and reported as warnings.
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	defaultCpus = 0.1 // cpus of the service if no resource limits
+	defaultMem  = 128 // mem (MB) of the service if no resource limits
+)
+
+// Parse convert the docker-compose file into the compose instance with one
+// service per compose service, the instance name and run as user are left to
+// the caller. it returns the warnings of the unsupported keys.
+func Parse(data []byte) (*types.Instance, []string, error) {
+	var file map[string]interface{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, nil, err
+	}
+
+	p := new(parser)
+	for key := range file {
+		switch key {
+		case "version", "services":
+		default:
+			p.warn(key, "unsupported")
+		}
+	}
+
+	services, ok := toMap(file["services"])
+	if !ok || len(services) == 0 {
+		return nil, nil, fmt.Errorf("services required")
+	}
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ins := &types.Instance{
+		Services: make([]*types.Service, 0, len(names)),
+	}
+	for _, name := range names {
+		svc, err := p.service(name, services[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		ins.Services = append(ins.Services, svc)
+	}
+
+	for _, svc := range ins.Services {
+		for _, dep := range svc.DependsOn {
+			if ins.Service(dep) == nil {
+				return nil, nil, fmt.Errorf("services.%s.depends_on: no such service %s", svc.Name, dep)
+			}
+		}
+	}
+	if _, err := ins.Order(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Strings(p.warnings)
+	return ins, p.warnings, nil
+}
+
+type parser struct {
+	warnings []string
+}
+
+func (p *parser) warn(field, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, field+": "+fmt.Sprintf(format, args...))
+}
+
+// service convert the compose service into the swan service
+func (p *parser) service(name string, v interface{}) (*types.Service, error) {
+	field := "services." + name
+
+	m, ok := toMap(v)
+	if !ok {
+		return nil, fmt.Errorf("%s: should be a mapping", field)
+	}
+
+	docker := &types.Docker{Network: "bridge"}
+	ver := &types.AppVersion{
+		Cpus:      defaultCpus,
+		Mem:       defaultMem,
+		Instances: 1,
+		Labels:    make(map[string]string),
+		Env:       make(map[string]string),
+		Container: &types.Container{Type: "docker", Docker: docker},
+	}
+	svc := &types.Service{Name: name, Version: ver}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := m[key]
+		f := field + "." + key
+
+		var err error
+		switch key {
+		case "image":
+			docker.Image = fmt.Sprint(val)
+		case "command":
+			ver.Command, err = toCommand(val)
+		case "environment":
+			ver.Env, err = toStringMap(val)
+		case "labels":
+			ver.Labels, err = toStringMap(val)
+		case "ports":
+			docker.PortMappings, err = p.ports(f, val)
+		case "volumes":
+			ver.Container.Volumes, err = p.volumes(f, val)
+		case "depends_on":
+			svc.DependsOn, err = toDependsOn(val)
+		case "deploy":
+			err = p.deploy(f, val, ver)
+		default:
+			p.warn(f, "unsupported")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+	}
+
+	if docker.Image == "" {
+		return nil, fmt.Errorf("%s.image: required", field)
+	}
+	return svc, nil
+}
+
+// ports convert the port mappings: `8080:80`, `80`, `8080:80/udp` or
+// `127.0.0.1:8080:80`, ranges and the long syntax are not supported.
+func (p *parser) ports(field string, v interface{}) ([]*types.PortMapping, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("should be a list")
+	}
+
+	ret := make([]*types.PortMapping, 0, len(list))
+	for i, item := range list {
+		spec := fmt.Sprint(item)
+		f := fmt.Sprintf("%s[%d]", field, i)
+
+		pm := &types.PortMapping{Protocol: "tcp"}
+		if idx := strings.Index(spec, "/"); idx >= 0 {
+			pm.Protocol = spec[idx+1:]
+			spec = spec[:idx]
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) == 3 {
+			p.warn(f, "host ip %s ignored", parts[0])
+			parts = parts[1:]
+		}
+		if strings.Contains(spec, "-") || len(parts) > 2 {
+			p.warn(f, "unsupported port %v", item)
+			continue
+		}
+
+		ctr, err := strconv.ParseInt(parts[len(parts)-1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %v", item)
+		}
+		pm.ContainerPort = int32(ctr)
+
+		if len(parts) == 2 {
+			host, err := strconv.ParseInt(parts[0], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %v", item)
+			}
+			pm.HostPort = int32(host)
+		}
+
+		ret = append(ret, pm)
+	}
+	return ret, nil
+}
+
+// volumes convert the bind mounts: `/host:/container` or `/host:/container:ro`,
+// the named volumes and the anonymous volumes are not supported.
+func (p *parser) volumes(field string, v interface{}) ([]*types.Volume, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("should be a list")
+	}
+
+	ret := make([]*types.Volume, 0, len(list))
+	for i, item := range list {
+		f := fmt.Sprintf("%s[%d]", field, i)
+
+		spec, ok := item.(string)
+		if !ok {
+			p.warn(f, "long syntax unsupported")
+			continue
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 || !filepath.IsAbs(parts[0]) {
+			p.warn(f, "only host path bind mounts supported, got %s", spec)
+			continue
+		}
+
+		vol := &types.Volume{HostPath: parts[0], ContainerPath: parts[1], Mode: "RW"}
+		if len(parts) == 3 {
+			vol.Mode = strings.ToUpper(parts[2])
+		}
+		ret = append(ret, vol)
+	}
+	return ret, nil
+}
+
+// deploy convert the replicas, the global mode and the resource limits
+func (p *parser) deploy(field string, v interface{}, ver *types.AppVersion) error {
+	m, ok := toMap(v)
+	if !ok {
+		return fmt.Errorf("should be a mapping")
+	}
+
+	for key, val := range m {
+		switch key {
+		case "replicas":
+			n, err := strconv.ParseInt(fmt.Sprint(val), 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid replicas %v", val)
+			}
+			ver.Instances = int32(n)
+
+		case "mode":
+			switch val {
+			case "replicated":
+			case "global":
+				ver.Mode = "daemon"
+			default:
+				return fmt.Errorf("invalid mode %v", val)
+			}
+
+		case "resources":
+			res, _ := toMap(val)
+			for k := range res {
+				if k != "limits" {
+					p.warn(field+".resources."+k, "unsupported")
+				}
+			}
+
+			limits, _ := toMap(res["limits"])
+			for k, lv := range limits {
+				switch k {
+				case "cpus":
+					cpus, err := strconv.ParseFloat(fmt.Sprint(lv), 64)
+					if err != nil {
+						return fmt.Errorf("invalid cpus %v", lv)
+					}
+					ver.Cpus = cpus
+				case "memory":
+					mem, err := parseMemory(fmt.Sprint(lv))
+					if err != nil {
+						return err
+					}
+					ver.Mem = mem
+				default:
+					p.warn(field+".resources.limits."+k, "unsupported")
+				}
+			}
+
+		default:
+			p.warn(field+"."+key, "unsupported")
+		}
+	}
+	return nil
+}
+
+// parseMemory parse the memory size like `512M`, `1g` or bytes into MB
+func parseMemory(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "b")
+
+	unit := 1.0 / 1024 / 1024 // bytes
+	for suffix, u := range map[string]float64{"k": 1.0 / 1024, "m": 1, "g": 1024} {
+		if strings.HasSuffix(s, suffix) {
+			s, unit = strings.TrimSuffix(s, suffix), u
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %s", s)
+	}
+	return v * unit, nil
+}
+
+// toCommand convert the string or the list form command into shell command,
+// the arguments of the list form are passed literally without any expansion.
+func toCommand(v interface{}) (string, error) {
+	switch c := v.(type) {
+	case string:
+		return c, nil
+	case []interface{}:
+		args := make([]string, 0, len(c))
+		for _, arg := range c {
+			args = append(args, shellQuote(fmt.Sprint(arg)))
+		}
+		return strings.Join(args, " "), nil
+	}
+	return "", fmt.Errorf("should be a string or a list")
+}
+
+// shellQuote quote the argument with single quotes, the embedded single
+// quotes are closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// toStringMap convert the `KEY=VAL` list or the mapping
+func toStringMap(v interface{}) (map[string]string, error) {
+	ret := make(map[string]string)
+
+	switch c := v.(type) {
+	case []interface{}:
+		for _, item := range c {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			ret[kv[0]] = kv[1]
+		}
+		return ret, nil
+
+	case map[interface{}]interface{}:
+		for key, val := range c {
+			if val == nil {
+				val = ""
+			}
+			ret[fmt.Sprint(key)] = fmt.Sprint(val)
+		}
+		return ret, nil
+	}
+
+	return nil, fmt.Errorf("should be a list or a mapping")
+}
+
+// toDependsOn convert the service list, or the mapping with conditions of v2.1
+func toDependsOn(v interface{}) ([]string, error) {
+	var ret []string
+
+	switch c := v.(type) {
+	case []interface{}:
+		for _, item := range c {
+			ret = append(ret, fmt.Sprint(item))
+		}
+	case map[interface{}]interface{}:
+		for key := range c {
+			ret = append(ret, fmt.Sprint(key))
+		}
+	default:
+		return nil, fmt.Errorf("should be a list or a mapping")
+	}
+
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// toMap convert the yaml mapping with string keys
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	ret := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		ret[fmt.Sprint(key)] = val
+	}
+	return ret, true
+}
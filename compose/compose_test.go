@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"testing"
+)
+
+var sample = `
+version: "3"
+services:
+  web:
+    image: nginx:1.11
+    command: ["nginx", "-g", "daemon off;"]
+    ports:
+      - "8080:80"
+      - "443"
+    volumes:
+      - /data/www:/usr/share/nginx/html:ro
+      - logs:/var/log/nginx
+    environment:
+      - MODE=prod
+    depends_on:
+      - db
+    restart: always
+    deploy:
+      replicas: 3
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 512M
+  db:
+    image: mysql:5.7
+    environment:
+      MYSQL_ROOT_PASSWORD: secret
+    deploy:
+      mode: global
+volumes:
+  logs:
+`
+
+func TestParse(t *testing.T) {
+	ins, warnings, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ins.Services) != 2 {
+		t.Fatalf("expect 2 services, got %d", len(ins.Services))
+	}
+
+	db, web := ins.Services[0], ins.Services[1]
+	if db.Name != "db" || db.Version.Env["MYSQL_ROOT_PASSWORD"] != "secret" || db.Version.Mode != "daemon" {
+		t.Errorf("unexpected db service: %+v", db.Version)
+	}
+
+	ver := web.Version
+	if ver.Instances != 3 || ver.Cpus != 0.5 || ver.Mem != 512 {
+		t.Errorf("unexpected web resources: instances %d, cpus %v, mem %v", ver.Instances, ver.Cpus, ver.Mem)
+	}
+	if ver.Command != `'nginx' '-g' 'daemon off;'` {
+		t.Errorf("unexpected web command: %s", ver.Command)
+	}
+	if ver.Env["MODE"] != "prod" {
+		t.Errorf("unexpected web env: %v", ver.Env)
+	}
+	if len(web.DependsOn) != 1 || web.DependsOn[0] != "db" {
+		t.Errorf("unexpected web depends on: %v", web.DependsOn)
+	}
+
+	pms := ver.Container.Docker.PortMappings
+	if len(pms) != 2 || pms[0].HostPort != 8080 || pms[0].ContainerPort != 80 || pms[1].HostPort != 0 || pms[1].ContainerPort != 443 {
+		t.Errorf("unexpected web ports: %+v %+v", pms[0], pms[1])
+	}
+
+	vols := ver.Container.Volumes
+	if len(vols) != 1 || vols[0].HostPath != "/data/www" || vols[0].Mode != "RO" {
+		t.Errorf("unexpected web volumes: %+v", vols)
+	}
+
+	expect := []string{
+		"services.web.restart: unsupported",
+		"services.web.volumes[1]: only host path bind mounts supported, got logs:/var/log/nginx",
+		"volumes: unsupported",
+	}
+	if len(warnings) != len(expect) {
+		t.Fatalf("expect warnings %v, got %v", expect, warnings)
+	}
+	for i := range expect {
+		if warnings[i] != expect[i] {
+			t.Errorf("expect warning %q, got %q", expect[i], warnings[i])
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	for s, mb := range map[string]float64{
+		"512M":      512,
+		"512mb":     512,
+		"1g":        1024,
+		"2048k":     2,
+		"134217728": 128,
+	} {
+		got, err := parseMemory(s)
+		if err != nil {
+			t.Errorf("parse %s error: %v", s, err)
+			continue
+		}
+		if got != mb {
+			t.Errorf("parse %s: expect %v, got %v", s, mb, got)
+		}
+	}
+}
+
+func TestToCommand(t *testing.T) {
+	got, err := toCommand([]interface{}{"echo", "$HOME", "`id`", "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `'echo' '$HOME' '` + "`id`" + `' 'it'\''s'`; got != expect {
+		t.Errorf("expect %s, got %s", expect, got)
+	}
+}
+
+func TestParseCircular(t *testing.T) {
+	data := `
+services:
+  a:
+    image: busybox
+    depends_on: [c]
+  b:
+    image: busybox
+    depends_on: [a]
+  c:
+    image: busybox
+    depends_on: [b]
+  d:
+    image: busybox
+`
+	if _, _, err := Parse([]byte(data)); err == nil {
+		t.Fatal("expect circular dependencies error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/urfave/cli"
@@ -39,6 +40,29 @@ var (
 			Usage:  "swan zookeeper path. eg. zk://host1:port1,host2:port2,.../swan",
 			EnvVar: "SWAN_ZK_URL",
 		},
+		cli.BoolFlag{
+			Name:   "preemption",
+			Usage:  "allow higher priority apps to preempt lower priority tasks when no offer fits",
+			EnvVar: "SWAN_PREEMPTION",
+		},
+		cli.DurationFlag{
+			Name:   "backoff-base",
+			Usage:  "base delay of relaunching the failed tasks, doubled on each consecutive failure",
+			EnvVar: "SWAN_BACKOFF_BASE",
+			Value:  time.Second,
+		},
+		cli.DurationFlag{
+			Name:   "backoff-max",
+			Usage:  "max delay of relaunching the failed tasks",
+			EnvVar: "SWAN_BACKOFF_MAX",
+			Value:  5 * time.Minute,
+		},
+		cli.IntFlag{
+			Name:   "version-retention",
+			Usage:  "nb of versions kept per app, the older ones are garbage collected",
+			EnvVar: "SWAN_VERSION_RETENTION",
+			Value:  10,
+		},
 	}
 )
 
@@ -114,7 +138,12 @@ func newMgrCfg(c *cli.Context) (*types.MgrConfig, error) {
 	}
 
 	cfg := &types.MgrConfig{
-		Listen: listen,
+		Listen:      listen,
+		Preemption:  c.Bool("preemption"),
+		BackoffBase: c.Duration("backoff-base"),
+		BackoffMax:  c.Duration("backoff-max"),
+
+		VersionRetention: c.Int("version-retention"),
 	}
 
 	if cfg.MesosURL, err = url.Parse(mesos); err != nil {
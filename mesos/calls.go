@@ -0,0 +1,123 @@
+package mesos
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bbklab/swan-ng/mesos/protobuf/mesos"
+	"github.com/bbklab/swan-ng/mesos/protobuf/sched"
+)
+
+// Events return the channel of received mesos events
+func (c *Client) Events() <-chan *sched.Event {
+	return c.eventCh
+}
+
+// Errors return the channel of mesos events subscriber's errors
+func (c *Client) Errors() <-chan error {
+	return c.errCh
+}
+
+// FrameworkID return current framework id, empty if not subscribed yet
+func (c *Client) FrameworkID() string {
+	return c.framework.GetId().GetValue()
+}
+
+// SetFrameworkID set the framework id which assigned by mesos master
+func (c *Client) SetFrameworkID(id string) {
+	c.framework.Id = &mesos.FrameworkID{
+		Value: proto.String(id),
+	}
+}
+
+// Launch accept the offers and launch tasks on them
+func (c *Client) Launch(offerIDs []*mesos.OfferID, tasks []*mesos.TaskInfo) error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_ACCEPT.Enum(),
+		Accept: &sched.Call_Accept{
+			OfferIds: offerIDs,
+			Operations: []*mesos.Offer_Operation{
+				{
+					Type: mesos.Offer_Operation_LAUNCH.Enum(),
+					Launch: &mesos.Offer_Operation_Launch{
+						TaskInfos: tasks,
+					},
+				},
+			},
+			Filters: &mesos.Filters{RefuseSeconds: proto.Float64(1)},
+		},
+	}
+
+	return c.Send(call)
+}
+
+// Decline decline the offers, refuse them for `refuse` seconds
+func (c *Client) Decline(offerIDs []*mesos.OfferID, refuse float64) error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_DECLINE.Enum(),
+		Decline: &sched.Call_Decline{
+			OfferIds: offerIDs,
+			Filters:  &mesos.Filters{RefuseSeconds: proto.Float64(refuse)},
+		},
+	}
+
+	return c.Send(call)
+}
+
+// Kill kill the task with the grace period (in seconds), zero means using mesos default
+func (c *Client) Kill(taskID, agentID string, gracePeriod int64) error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_KILL.Enum(),
+		Kill: &sched.Call_Kill{
+			TaskId:  &mesos.TaskID{Value: proto.String(taskID)},
+			AgentId: &mesos.AgentID{Value: proto.String(agentID)},
+		},
+	}
+
+	if gracePeriod > 0 {
+		call.Kill.KillPolicy = &mesos.KillPolicy{
+			GracePeriod: &mesos.DurationInfo{
+				Nanoseconds: proto.Int64(gracePeriod * 1000 * 1000 * 1000),
+			},
+		}
+	}
+
+	return c.Send(call)
+}
+
+// Acknowledge acknowledge the task status update
+func (c *Client) Acknowledge(status *mesos.TaskStatus) error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_ACKNOWLEDGE.Enum(),
+		Acknowledge: &sched.Call_Acknowledge{
+			AgentId: status.GetAgentId(),
+			TaskId:  status.GetTaskId(),
+			Uuid:    status.GetUuid(),
+		},
+	}
+
+	return c.Send(call)
+}
+
+// Revive remove all of the offer filters, ask mesos for offers again
+func (c *Client) Revive() error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_REVIVE.Enum(),
+	}
+
+	return c.Send(call)
+}
+
+// Suppress ask mesos to stop sending offers
+func (c *Client) Suppress() error {
+	call := &sched.Call{
+		FrameworkId: c.framework.GetId(),
+		Type:        sched.Call_SUPPRESS.Enum(),
+	}
+
+	return c.Send(call)
+}
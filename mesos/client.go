@@ -1,13 +1,17 @@
 package mesos
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -28,6 +32,7 @@ type Client struct {
 
 	endPoint string // eg: http://master/api/v1/scheduler
 	cluster  string // name of mesos cluster
+	streamID string // Mesos-Stream-Id, required by all of calls after subscribed
 }
 
 // NewClient ...
@@ -113,6 +118,9 @@ func (c *Client) send(call *sched.Call) (*http.Response, error) {
 	}
 	req.Header.Set("Content-Type", "application/x-protobuf")
 	req.Header.Set("Accept", "application/json")
+	if c.streamID != "" {
+		req.Header.Set("Mesos-Stream-Id", c.streamID)
+	}
 
 	return c.http.Do(req)
 }
@@ -151,6 +159,7 @@ func (c *Client) Subscribe() error {
 		resp.Body.Close()
 		return fmt.Errorf("subscribe with unexpected response [%d] - [%s]", code, string(bs))
 	}
+	c.streamID = resp.Header.Get("Mesos-Stream-Id")
 	log.Printf("subscribed to mesos leader: %s", c.endPoint)
 
 	go c.watchEvents(resp)
@@ -166,13 +175,13 @@ func (c *Client) watchEvents(resp *http.Response) {
 	}()
 
 	var (
-		dec = json.NewDecoder(resp.Body)
-		ev  = new(sched.Event)
+		rd  = bufio.NewReader(resp.Body)
 		err error
 	)
 
 	for {
-		err = dec.Decode(&ev)
+		ev := new(sched.Event)
+		err = readRecord(rd, ev)
 		if err != nil {
 			log.Errorln("mesos events subscriber decode events error:", err)
 			c.errCh <- err
@@ -182,3 +191,23 @@ func (c *Client) watchEvents(resp *http.Response) {
 		c.eventCh <- ev
 	}
 }
+
+// readRecord read one RecordIO framed event: "<length>\n<json-data>"
+func readRecord(rd *bufio.Reader, ev *sched.Event) error {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		return fmt.Errorf("invalid record length %q: %v", line, err)
+	}
+
+	bs := make([]byte, size)
+	if _, err := io.ReadFull(rd, bs); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bs, ev)
+}
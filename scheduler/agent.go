@@ -0,0 +1,357 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+var (
+	// ErrNoSuchAgent represents the agent is unknown to the scheduler
+	ErrNoSuchAgent = errors.New("no such agent")
+)
+
+// loadAgents load the agents' maintenance states from the store
+func (s *Scheduler) loadAgents() error {
+	agents, err := store.DB().ListAgents()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, a := range agents {
+		s.maint[a.ID] = a
+	}
+	return nil
+}
+
+// schedulable check if the tasks could be placed on the agent
+// NOTE the caller should hold the lock
+func (s *Scheduler) schedulable(agentID string) error {
+	if m, ok := s.maint[agentID]; ok && m.State != types.AgentActive {
+		return fmt.Errorf("agent is %s", m.State)
+	}
+	return nil
+}
+
+// fit check if the node is schedulable and could host one instance of the app version
+// NOTE the caller should hold the lock
+func (s *Scheduler) fit(n *node, ver *types.AppVersion) error {
+	if err := s.schedulable(n.agent.id); err != nil {
+		return err
+	}
+	return n.fit(ver)
+}
+
+// Agents return all of the known agents with their maintenance states
+func (s *Scheduler) Agents() ([]*types.Agent, error) {
+	counts, err := taskCountByAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	m := make(map[string]*types.Agent)
+	for id, a := range s.agents {
+		m[id] = &types.Agent{
+			ID:       id,
+			Hostname: a.hostname,
+			State:    types.AgentActive,
+			Attrs:    a.attrs,
+		}
+	}
+	for id, maint := range s.maint {
+		a, ok := m[id]
+		if !ok {
+			a = &types.Agent{ID: id, Hostname: maint.Hostname}
+			m[id] = a
+		}
+		a.State = maint.State
+		a.UpdatedAt = maint.UpdatedAt
+	}
+	for _, o := range s.offers {
+		if a, ok := m[o.agentID]; ok {
+			a.CachedOffers++
+			a.OfferedCpus += o.cpus
+			a.OfferedMem += o.mem
+			a.OfferedDisk += o.disk
+			a.OfferedPorts += len(o.ports)
+		}
+	}
+
+	ret := make([]*types.Agent, 0, len(m))
+	for id, a := range m {
+		a.Tasks = counts[id]
+		a.Schedulable = s.schedulable(id) == nil
+		ret = append(ret, a)
+	}
+	sort.Sort(agentSorter(ret))
+	return ret, nil
+}
+
+// Blacklist stop placing tasks on the agent, the existing tasks are untouched
+func (s *Scheduler) Blacklist(id string) error {
+	return s.setAgentState(id, types.AgentBlacklisted)
+}
+
+// Drain stop placing tasks on the agent and migrate the existing tasks off it.
+// by default the tasks are migrated one by one per app, a replacement is launched
+// and becomes healthy before the task on the agent being killed, so the apps keep
+// their healthy capacity. with force, all of the tasks are killed immediately and
+// relaunched elsewhere.
+func (s *Scheduler) Drain(id string, force bool) error {
+	if err := s.setAgentState(id, types.AgentDraining); err != nil {
+		return err
+	}
+
+	if !force {
+		go s.drainAgents()
+		return nil
+	}
+
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		for _, t := range s.aliveTasksOn(app.ID, id) {
+			log.Printf("force draining task %s off agent %s", t.ID, id)
+			if err := s.KillTask(t, app.Version.KillPolicy, true); err != nil {
+				log.Errorf("kill task %s error: %v", t.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Activate bring the agent back to be schedulable
+func (s *Scheduler) Activate(id string) error {
+	return s.setAgentState(id, types.AgentActive)
+}
+
+func (s *Scheduler) setAgentState(id, state string) error {
+	s.Lock()
+	var (
+		a, known     = s.agents[id]
+		maint, inMnt = s.maint[id]
+		prev         = types.AgentActive
+	)
+	if !known && !inMnt {
+		s.Unlock()
+		return ErrNoSuchAgent
+	}
+	if inMnt {
+		prev = maint.State
+	}
+	s.Unlock()
+
+	if prev == state {
+		return nil
+	}
+
+	record := &types.AgentMaintenance{
+		ID:        id,
+		State:     state,
+		UpdatedAt: time.Now().UnixNano(),
+	}
+	if known {
+		record.Hostname = a.hostname
+	} else {
+		record.Hostname = maint.Hostname
+	}
+
+	var err error
+	if state == types.AgentActive {
+		err = store.DB().DeleteAgent(id)
+	} else {
+		err = store.DB().UpdateAgent(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	if state == types.AgentActive {
+		delete(s.maint, id)
+	} else {
+		s.maint[id] = record
+	}
+	s.Unlock()
+
+	log.Printf("agent %s (%s) state changed: %s -> %s", id, record.Hostname, prev, state)
+	s.emit(&types.Event{
+		ID:     id,
+		Status: state,
+		From:   prev,
+		Time:   time.Now(),
+	})
+	return nil
+}
+
+// drainAgents make progress on migrating tasks off the draining agents
+func (s *Scheduler) drainAgents() {
+	s.Lock()
+	draining := make([]string, 0)
+	for id, m := range s.maint {
+		if m.State == types.AgentDraining {
+			draining = append(draining, id)
+		}
+	}
+	s.Unlock()
+
+	if len(draining) == 0 {
+		return
+	}
+
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		log.Errorf("drain agents error: %v", err)
+		return
+	}
+
+	for _, id := range draining {
+		var remaining int
+		for _, app := range apps {
+			n, err := s.drainApp(app, id)
+			if err != nil {
+				log.Errorf("drain app %s off agent %s error: %v", app.ID, id, err)
+			}
+			remaining += n
+		}
+
+		if remaining == 0 {
+			if err := s.setAgentState(id, types.AgentDrained); err != nil {
+				log.Errorf("mark agent %s drained error: %v", id, err)
+			}
+		}
+	}
+}
+
+// drainApp migrate one of the app's tasks off the agent once the app has
+// more healthy tasks than desired, otherwise launch a surge task first.
+// the decision is made under the lock, so the concurrent drains never surge
+// or kill twice for the same task.
+// it returns the nb of app's tasks remaining on the agent.
+func (s *Scheduler) drainApp(app *types.App, agentID string) (int, error) {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return 1, err // unknown, keep draining
+	}
+
+	s.Lock()
+	var (
+		onAgent        = make([]*types.Task, 0)
+		alive, healthy int
+	)
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; ok || !isAlive(t.State) {
+			continue
+		}
+		alive++
+		if t.State == "TASK_RUNNING" && t.Healthy != "unhealthy" {
+			healthy++
+		}
+		if t.AgentID == agentID {
+			onAgent = append(onAgent, t)
+		}
+	}
+
+	if len(onAgent) == 0 || app.Version == nil || app.Version.IsJob() || app.Version.IsDaemon() {
+		s.Unlock()
+		return len(onAgent), nil // the job tasks run to completion, the daemon tasks are killed by syncDaemons
+	}
+	switch app.State {
+	case types.AppDeleting, types.AppUpdating, types.AppCanary, types.AppSuspended:
+		s.Unlock()
+		return len(onAgent), nil // retry after done
+	}
+
+	var (
+		desired = int(app.Version.Instances)
+		pending = s.queue.countApp(app.ID)
+		victim  *types.Task
+		surge   bool
+	)
+	switch {
+	case healthy > desired:
+		victim = onAgent[0]
+		s.killing[victim.ID] = &killing{}
+	case alive+pending <= desired:
+		s.push(app.ID, app.Version, 1, 0)
+		surge = true
+	}
+	s.Unlock()
+
+	if victim != nil {
+		log.Printf("draining task %s off agent %s", victim.ID, agentID)
+		return len(onAgent), s.sendKill(victim, app.Version.KillPolicy)
+	}
+
+	if surge {
+		log.Printf("launching a surge task for app %s to drain agent %s", app.ID, agentID)
+		s.reviveIfNeeded()
+		s.schedule()
+	}
+
+	return len(onAgent), nil
+}
+
+// aliveTasksOn return the app's alive tasks on the agent which are not being killed
+func (s *Scheduler) aliveTasksOn(appID, agentID string) []*types.Task {
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	ret := make([]*types.Task, 0)
+	for _, t := range tasks {
+		if t.AgentID != agentID || !isAlive(t.State) {
+			continue
+		}
+		if _, ok := s.killing[t.ID]; ok {
+			continue
+		}
+		ret = append(ret, t)
+	}
+	return ret
+}
+
+func taskCountByAgent() (map[string]int, error) {
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]int)
+	for _, app := range apps {
+		tasks, err := store.DB().ListTasks(app.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			if isAlive(t.State) {
+				ret[t.AgentID]++
+			}
+		}
+	}
+	return ret, nil
+}
+
+// agentSorter sort agents by hostname
+type agentSorter []*types.Agent
+
+func (s agentSorter) Len() int           { return len(s) }
+func (s agentSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s agentSorter) Less(i, j int) bool { return s[i].Hostname < s[j].Hostname }
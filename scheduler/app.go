@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// NewApp build the new app of the version in the cluster, the app id
+// is derived from the app name, the run as user and the cluster.
+func NewApp(cluster string, ver *types.AppVersion) *types.App {
+	id := types.ObjectID(ver.AppName, ver.RunAs, cluster)
+	StampVersion(id, ver)
+
+	now := time.Now().UnixNano()
+	app := &types.App{
+		ID:        id,
+		Name:      ver.AppName,
+		ClusterID: cluster,
+		CreatedAt: now,
+		UpdatedAt: now,
+		State:     types.AppCreating,
+		Version:   ver,
+	}
+	if ver.IsJob() {
+		p := jobPolicy(ver)
+		ver.Instances = int32(minInt(int(p.Parallelism), int(p.Completions)))
+		app.Job = &types.JobStatus{Status: types.JobRunning, StartedAt: now}
+	}
+	if ver.IsDaemon() {
+		ver.Instances = 0 // derived once created
+	} else if ver.Instances == 0 {
+		app.State = types.AppNormal
+	}
+	return app
+}
+
+// CreateApp persist the new app with it's initial version,
+// and queue the desired instances for launching.
+// the daemon app's instances are queued on each of the matching agents.
+// it fails with the store's exists error if the app id is taken.
+func (s *Scheduler) CreateApp(app *types.App) error {
+	if err := store.DB().CreateApp(app); err != nil {
+		return err
+	}
+
+	if err := s.SaveVersion(app.ID, app.Version); err != nil {
+		return err
+	}
+
+	if app.Version.IsDaemon() {
+		s.syncDaemon(app)
+		return nil
+	}
+
+	s.Enqueue(app, int(app.Version.Instances))
+	return nil
+}
+
+// updateAppState persist the new state with the changes onto the stored app, and emit
+// an event on transition. the other fields of the given app are not persisted, as they
+// may be stale, the given app is refreshed with the stored one once persisted.
+// it returns *types.StateError if the transition is not allowed from the stored state.
+func (s *Scheduler) updateAppState(app *types.App, state string, changes ...func(*types.App)) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	cur, err := store.DB().GetApp(app.ID)
+	if err != nil {
+		log.Errorf("get app %s error: %v", app.ID, err)
+		return err
+	}
+
+	prev := cur.State
+	if err := types.CheckTransition(prev, state); err != nil {
+		log.Warnf("app %s: %v", app.ID, err)
+		return err
+	}
+
+	for _, change := range changes {
+		change(cur)
+	}
+	cur.State = state
+	cur.UpdatedAt = time.Now().UnixNano()
+
+	if err := store.DB().UpdateApp(cur); err != nil {
+		log.Errorf("update app %s error: %v", app.ID, err)
+		return err
+	}
+	*app = *cur
+
+	if prev != state {
+		s.emit(&types.Event{
+			ID:     app.ID,
+			Status: state,
+			From:   prev,
+			Time:   time.Now(),
+		})
+	}
+	return nil
+}
+
+// checkAppReady bring the creating or scaling app to normal once it has exactly
+// the desired instances alive, and all of them are ready, see isReady.
+// the creating job app is brought to normal once any of it's tasks running.
+func (s *Scheduler) checkAppReady(appID string) {
+	app, err := store.DB().GetApp(appID)
+	if err != nil {
+		return
+	}
+	if app.State != types.AppCreating && app.State != types.AppScaling {
+		return
+	}
+	if app.Version.IsJob() {
+		if app.State == types.AppCreating {
+			s.updateAppState(app, types.AppNormal)
+		}
+		return
+	}
+
+	alive, ready, err := countReady(app)
+	if err != nil {
+		return
+	}
+
+	desired := int(app.Version.Instances)
+	if ready >= desired && alive <= desired {
+		s.updateAppState(app, types.AppNormal)
+	}
+}
+
+// countReady count the alive tasks of the app, and the ready ones of them
+func countReady(app *types.App) (alive, ready int, err error) {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, t := range tasks {
+		if isAlive(t.State) {
+			alive++
+		}
+		if isReady(t, app.Version) {
+			ready++
+		}
+	}
+	return alive, ready, nil
+}
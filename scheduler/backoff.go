@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	crashLoopThreshold = 3               // nb of consecutive failures to be treated as crash looping
+	backoffResetAfter  = 2 * time.Minute // reset the backoff after the app's task keeps running so long
+)
+
+// backoff tracks the relaunch backoff status of an app with failing tasks
+type backoff struct {
+	failures     int       // consecutive failures
+	failovers    int       // total relaunches of the failed tasks
+	until        time.Time // relaunches are delayed until
+	runningSince time.Time // since when a task of the app keeps running, zero if none
+	prevState    string    // the app state before entering crash looping
+}
+
+// delay calculate the exponential relaunch delay by the nb of consecutive failures
+func (b *backoff) delay(base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < b.failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// recordFailure update the app's backoff status after one of it's tasks failed,
+// the app will be marked as crash looping after failed too many times in a row,
+// and as failed if exceeded the UpdatePolicy's MaxRetries or MaxFailovers.
+// while the app is being updated, the state is left to the update, and the
+// current tasks are always relaunched with the backoff.
+// it returns the relaunch delay and whether the failed task should be relaunched.
+func (s *Scheduler) recordFailure(app *types.App, task *types.Task) (time.Duration, bool) {
+	s.Lock()
+	b, ok := s.backoffs[app.ID]
+	if !ok {
+		b = &backoff{}
+		s.backoffs[app.ID] = b
+	}
+	b.failures++
+	b.failovers++
+	b.runningSince = time.Time{}
+	delay := b.delay(s.cfg.BackoffBase, s.cfg.BackoffMax)
+	b.until = time.Now().Add(delay)
+	failures, failovers, until := b.failures, b.failovers, b.until
+	s.Unlock()
+
+	updating := app.State == types.AppUpdating || app.State == types.AppCanary
+
+	var gaveUp bool
+	if p := app.Version.UpdatePolicy; p != nil && !updating {
+		gaveUp = (p.MaxRetries > 0 && failures > int(p.MaxRetries)) ||
+			(p.MaxFailovers > 0 && failovers > int(p.MaxFailovers))
+	}
+
+	bo := &types.Backoff{
+		Failures:    failures,
+		Failovers:   failovers,
+		Delay:       delay.String(),
+		Until:       until.UnixNano(),
+		LastReason:  task.Reason,
+		LastMessage: task.Message,
+	}
+
+	state := app.State
+	switch {
+	case updating:
+	case gaveUp:
+		state = types.AppFailed
+		log.Warnf("app %s failed %d times in a row (%d failovers), stop relaunching", app.ID, failures, failovers)
+	case failures >= crashLoopThreshold:
+		if app.State != types.AppCrashLooping {
+			s.Lock()
+			b.prevState = app.State
+			s.Unlock()
+		}
+		state = types.AppCrashLooping
+	}
+	s.updateAppState(app, state, func(a *types.App) { a.Backoff = bo })
+
+	return delay, !gaveUp
+}
+
+// markRunning record the app's task is running (and not unhealthy)
+func (s *Scheduler) markRunning(appID string, running bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	b, ok := s.backoffs[appID]
+	if !ok {
+		return
+	}
+
+	if !running {
+		b.runningSince = time.Time{}
+		return
+	}
+	if b.runningSince.IsZero() {
+		b.runningSince = time.Now()
+	}
+}
+
+// resetStableBackoffs forget the backoff of the apps which have recovered,
+// and bring the crash looping apps back to their previous state.
+func (s *Scheduler) resetStableBackoffs() {
+	s.Lock()
+	recovered := make(map[string]string) // app id -> previous state
+	for id, b := range s.backoffs {
+		if b.failures == 0 || b.runningSince.IsZero() {
+			continue
+		}
+		if time.Since(b.runningSince) < backoffResetAfter {
+			continue
+		}
+		delete(s.backoffs, id)
+		recovered[id] = b.prevState
+	}
+	s.Unlock()
+
+	for id, prevState := range recovered {
+		app, err := store.DB().GetApp(id)
+		if err != nil {
+			continue
+		}
+
+		log.Printf("app %s recovered from failures", id)
+		state := app.State
+		if state == types.AppCrashLooping {
+			state = prevState
+			if state == "" {
+				state = types.AppNormal // crash looping since before the restart
+			}
+		}
+		s.updateAppState(app, state, func(a *types.App) {
+			if a.Backoff != nil {
+				a.Backoff.Failures = 0
+				a.Backoff.Delay = ""
+				a.Backoff.Until = 0
+			}
+		})
+	}
+}
+
+// ResetBackoff forget the app's failures, so it's tasks could be launched without delay
+func (s *Scheduler) ResetBackoff(appID string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.backoffs, appID)
+}
@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+var (
+	// ErrNoBlueGreen represents the app has no ongoing blue/green update
+	ErrNoBlueGreen = errors.New("app has no ongoing blue/green update")
+)
+
+// blueGreenUpdate launch a full set of green tasks of the new version without
+// traffic, wait until all of them are healthy, then switch the traffic from the
+// blue tasks to them at once. the blue tasks are kept for the drain period, so
+// that the traffic could be switched back instantly on request or on failure.
+func (s *Scheduler) blueGreenUpdate(app *types.App, u *update) {
+	var (
+		maxRetries = int(u.to.UpdatePolicy.MaxRetries)
+		drain      time.Duration
+	)
+	if b := u.to.UpdatePolicy.BlueGreen; b != nil {
+		drain = time.Duration(b.DrainSeconds) * time.Second
+	}
+
+	app.Progress.Active = "blue"
+
+	s.Lock()
+	ids := s.pushNew(app.ID, u, int(u.to.Instances))
+	s.Unlock()
+
+	s.reviveIfNeeded()
+	s.schedule()
+
+	if err := s.waitReady(app, u, ids, maxRetries); err != nil {
+		if err == errUpdateAborted && !s.canceled(u) {
+			return
+		}
+		s.discard(app, u, err)
+		return
+	}
+
+	if err := s.switchTraffic(app.ID, u, true); err != nil {
+		s.discard(app, u, fmt.Errorf("switch traffic error: %v", err))
+		return
+	}
+
+	s.Lock()
+	u.switched = true
+	u.parallel = false // no more blue tasks
+	base := u.failures
+	s.Unlock()
+
+	drainUntil := time.Now().Add(drain)
+	app.Progress.Active = "green"
+	app.Progress.DrainUntil = drainUntil.UnixNano()
+	log.Printf("app %s switched to the green tasks, draining the blue tasks until %v", app.ID, drainUntil)
+
+	for {
+		s.Lock()
+		var (
+			aborted    = u.aborted
+			switchBack = u.switchBack
+			failures   = u.failures
+			lastErr    = u.lastError
+		)
+		s.Unlock()
+
+		switch {
+		case aborted:
+			return
+		case switchBack:
+			s.switchBack(app, u, nil)
+			return
+		case failures > base:
+			s.switchBack(app, u, fmt.Errorf("green task failed: %s", lastErr))
+			return
+		}
+
+		_, blues, err := s.splitTasks(app.ID, u)
+		if err == nil {
+			err = s.saveProgress(app, u, blues)
+		}
+		if err == errUpdateAborted {
+			return
+		}
+
+		if !time.Now().Before(drainUntil) {
+			break
+		}
+		time.Sleep(updatePollInterval)
+	}
+
+	_, blues, err := s.splitTasks(app.ID, u)
+	if err == nil {
+		timeout := updateKillTimeout
+		if p := u.from.KillPolicy; p != nil {
+			timeout += time.Duration(p.Duration) * time.Second
+		}
+		err = s.KillAndWait(blues, u.from.KillPolicy, false, timeout)
+	}
+	if err != nil {
+		log.Errorf("kill blue tasks of app %s error: %v", app.ID, err)
+	}
+
+	if s.finishUpdate(app.ID, u, nil) {
+		return
+	}
+
+	log.Printf("app %s updated", app.ID)
+	s.updateAppState(app, types.AppNormal, func(a *types.App) {
+		a.Version = u.to
+		a.ProposedVersion = nil
+		a.Progress = nil
+	})
+}
+
+// SwitchBack route the traffic back to the blue tasks and kill the green tasks,
+// the update is aborted if the traffic has not been switched yet.
+func (s *Scheduler) SwitchBack(appID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, ok := s.updates[appID]
+	if !ok || u.strategy != "bluegreen" {
+		return ErrNoBlueGreen
+	}
+
+	if u.switched {
+		u.switchBack = true
+	} else {
+		u.cancel = true
+	}
+	return nil
+}
+
+func (s *Scheduler) switchBack(app *types.App, u *update, cause error) {
+	log.Printf("switching app %s back to the blue tasks", app.ID)
+	if err := s.switchTraffic(app.ID, u, false); err != nil {
+		log.Errorf("switch app %s back error: %v", app.ID, err)
+	}
+	app.Progress.Active = "blue"
+	s.discard(app, u, cause)
+}
+
+// switchTraffic route all of the app's traffic to the green or the blue tasks atomically
+func (s *Scheduler) switchTraffic(appID string, u *update, green bool) error {
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	changed := make([]*types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !isAlive(t.State) {
+			continue
+		}
+		weight := float64(0)
+		if u.tasks[t.ID] == green {
+			weight = defaultTaskWeight
+		}
+		if t.Weight != weight {
+			t.Weight = weight
+			changed = append(changed, t)
+		}
+	}
+	s.Unlock()
+
+	return store.DB().UpdateTasks(appID, changed)
+}
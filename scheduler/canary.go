@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+var (
+	// ErrNoCanary represents the app has no ongoing canary
+	ErrNoCanary = errors.New("app has no ongoing canary")
+	// ErrCanaryNotReady represents the canary tasks are not running and healthy yet
+	ErrCanaryNotReady = errors.New("canary tasks are not ready yet")
+)
+
+// canaryUpdate launch the canary tasks of the new version alongside the current
+// tasks and route a part of traffic to them. the canary is promoted to a rolling
+// update on request, or automatically after the soak period without failures,
+// and is aborted on request or once any of the canary tasks failed.
+func (s *Scheduler) canaryUpdate(app *types.App, u *update) {
+	var (
+		c          = u.to.UpdatePolicy.Canary
+		maxRetries = int(u.to.UpdatePolicy.MaxRetries)
+	)
+
+	s.Lock()
+	ids := s.pushNew(app.ID, u, int(c.Instances))
+	s.Unlock()
+
+	s.reviveIfNeeded()
+	s.schedule()
+
+	if err := s.waitReady(app, u, ids, maxRetries); err != nil {
+		if err == errUpdateAborted && !s.canceled(u) {
+			return
+		}
+		s.discard(app, u, err)
+		return
+	}
+
+	s.Lock()
+	u.soaking = true
+	base := u.failures
+	s.Unlock()
+
+	var soakUntil time.Time
+	if c.SoakSeconds > 0 {
+		soakUntil = time.Now().Add(time.Duration(c.SoakSeconds) * time.Second)
+		app.Progress.SoakUntil = soakUntil.UnixNano()
+	}
+	log.Printf("canary of app %s is ready, soaking until %v", app.ID, soakUntil)
+
+	for {
+		s.Lock()
+		var (
+			aborted  = u.aborted
+			cancel   = u.cancel
+			promote  = u.promote
+			failures = u.failures
+			lastErr  = u.lastError
+		)
+		s.Unlock()
+
+		switch {
+		case aborted:
+			return
+		case cancel:
+			s.discard(app, u, nil)
+			return
+		case failures > base:
+			s.discard(app, u, fmt.Errorf("canary task failed: %s", lastErr))
+			return
+		case promote:
+			s.promoteCanary(app, u)
+			return
+		case !soakUntil.IsZero() && time.Now().After(soakUntil):
+			log.Printf("canary of app %s soaked without failures, promoting", app.ID)
+			s.promoteCanary(app, u)
+			return
+		}
+
+		if err := s.canaryWeights(app.ID, u); err != nil {
+			log.Errorf("set canary weights of app %s error: %v", app.ID, err)
+		}
+
+		_, olds, err := s.splitTasks(app.ID, u)
+		if err == nil {
+			err = s.saveProgress(app, u, olds)
+		}
+		if err == errUpdateAborted {
+			return
+		}
+
+		time.Sleep(updatePollInterval)
+	}
+}
+
+// PromoteCanary roll the rest of the app's tasks to the canary version
+func (s *Scheduler) PromoteCanary(appID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, ok := s.updates[appID]
+	if !ok || u.strategy != "canary" || u.promote {
+		return ErrNoCanary
+	}
+	if !u.soaking {
+		return ErrCanaryNotReady
+	}
+
+	u.promote = true
+	return nil
+}
+
+// AbortCanary kill the canary tasks and keep the app on the current version
+func (s *Scheduler) AbortCanary(appID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, ok := s.updates[appID]
+	if !ok || u.strategy != "canary" || u.promote {
+		return ErrNoCanary
+	}
+
+	u.cancel = true
+	return nil
+}
+
+func (s *Scheduler) canceled(u *update) bool {
+	s.Lock()
+	defer s.Unlock()
+	return u.cancel
+}
+
+// promoteCanary reset the traffic weights and continue as a rolling update,
+// the canary tasks are counted as the updated instances.
+func (s *Scheduler) promoteCanary(app *types.App, u *update) {
+	s.Lock()
+	u.soaking = false
+	u.parallel = false
+	s.Unlock()
+
+	if err := s.resetWeights(app.ID); err != nil {
+		log.Errorf("reset weights of app %s error: %v", app.ID, err)
+	}
+
+	log.Printf("promoting canary of app %s", app.ID)
+	s.updateAppState(app, types.AppUpdating, func(a *types.App) {
+		if a.Progress != nil {
+			a.Progress.SoakUntil = 0
+		}
+	})
+
+	s.rollingUpdate(app, u)
+}
+
+// canaryWeights route the canary version's `Gateway.Weight` percent of traffic to
+// the canary tasks, and the rest to the current tasks.
+func (s *Scheduler) canaryWeights(appID string, u *update) error {
+	g := u.to.Gateway
+	if g == nil || g.Weight <= 0 {
+		return nil // evenly shared
+	}
+
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	canaries := make([]*types.Task, 0)
+	currents := make([]*types.Task, 0)
+	for _, t := range tasks {
+		if !isAlive(t.State) {
+			continue
+		}
+		if u.tasks[t.ID] {
+			canaries = append(canaries, t)
+		} else {
+			currents = append(currents, t)
+		}
+	}
+	s.Unlock()
+
+	if len(canaries) == 0 || len(currents) == 0 {
+		return s.resetWeights(appID)
+	}
+
+	for _, t := range canaries {
+		if err := setWeight(appID, t, g.Weight/float64(len(canaries))); err != nil {
+			return err
+		}
+	}
+	for _, t := range currents {
+		if err := setWeight(appID, t, (100-g.Weight)/float64(len(currents))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	defaultStartTimeout = 5 * time.Minute // default timeout of waiting for the service's dependencies ready
+)
+
+// CreateInstance create the member apps of the compose instance in background in
+// the dependency order, each app is created once all of the apps it depends on are
+// running and healthy. the instance is marked failed if any of the apps failed to be
+// created or any of the dependencies not ready in time, the created apps are kept so
+// the operator could inspect and delete them.
+func (s *Scheduler) CreateInstance(ins *types.Instance) error {
+	order, err := ins.Order()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	ins.Status = types.InstanceCreating
+	ins.CreatedAt = now
+	ins.UpdatedAt = now
+
+	for _, svc := range ins.Services {
+		svc.AppID = types.ObjectID(svc.Version.AppName, svc.Version.RunAs, ins.ClusterID)
+	}
+
+	if err := store.DB().CreateInstance(ins); err != nil {
+		return err
+	}
+
+	go s.createServices(ins, order)
+	return nil
+}
+
+// resumeInstances resume creating the compose instances left creating by the previous
+// manager, the member apps already created are skipped.
+func (s *Scheduler) resumeInstances() error {
+	inss, err := store.DB().ListInstances()
+	if err != nil {
+		return err
+	}
+
+	for _, ins := range inss {
+		if ins.Status != types.InstanceCreating {
+			continue
+		}
+
+		order, err := ins.Order()
+		if err != nil {
+			s.updateInstance(ins, types.InstanceFailed, err.Error())
+			continue
+		}
+
+		log.Warnf("the creating of compose instance %s was interrupted by the manager restart, resuming", ins.ID)
+		go s.createServices(ins, order)
+	}
+	return nil
+}
+
+func (s *Scheduler) createServices(ins *types.Instance, order []*types.Service) {
+	for _, svc := range order {
+		if err := s.waitDependencies(ins, svc); err != nil {
+			s.finishCreating(ins, types.InstanceFailed, err.Error())
+			return
+		}
+
+		if !s.stillCreating(ins.ID) {
+			return
+		}
+
+		if _, err := store.DB().GetApp(svc.AppID); err == nil {
+			continue // created before the manager restart
+		}
+
+		app := NewApp(ins.ClusterID, svc.Version)
+		if err := s.CreateApp(app); err != nil {
+			err = fmt.Errorf("create app of service %s error: %v", svc.Name, err)
+			s.finishCreating(ins, types.InstanceFailed, err.Error())
+			return
+		}
+		log.Printf("compose instance %s: app %s of service %s created", ins.ID, app.ID, svc.Name)
+	}
+
+	s.finishCreating(ins, types.InstanceCreated, "")
+}
+
+// finishCreating update the compose instance's status unless it's being deleted meanwhile
+func (s *Scheduler) finishCreating(ins *types.Instance, status, errmsg string) {
+	if s.stillCreating(ins.ID) {
+		s.updateInstance(ins, status, errmsg)
+	}
+}
+
+// stillCreating check if the compose instance is still being created
+func (s *Scheduler) stillCreating(id string) bool {
+	cur, err := store.DB().GetInstance(id)
+	return err == nil && cur.Status == types.InstanceCreating
+}
+
+// waitDependencies wait until the apps of the service's dependencies are normal,
+// and all of their desired instances are ready, see isReady.
+func (s *Scheduler) waitDependencies(ins *types.Instance, svc *types.Service) error {
+	timeout := defaultStartTimeout
+	if svc.StartTimeout > 0 {
+		timeout = time.Duration(svc.StartTimeout) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	for _, name := range svc.DependsOn {
+		dep := ins.Service(name)
+		if dep == nil {
+			continue
+		}
+
+		for {
+			if !s.stillCreating(ins.ID) {
+				return fmt.Errorf("compose instance is not being created any more")
+			}
+
+			app, err := store.DB().GetApp(dep.AppID)
+			if err != nil {
+				return fmt.Errorf("service %s depends on %s: %v", svc.Name, name, err)
+			}
+			if app.State == types.AppNormal {
+				_, ready, err := countReady(app)
+				if err == nil && ready >= int(app.Version.Instances) {
+					break
+				}
+			}
+			if app.State == types.AppFailed {
+				return fmt.Errorf("service %s depends on %s which is failed: %s", svc.Name, name, app.ErrMsg)
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("service %s depends on %s which is not ready in %s", svc.Name, name, timeout)
+			}
+
+			time.Sleep(updatePollInterval)
+		}
+	}
+	return nil
+}
+
+// DeleteInstance delete the member apps of the compose instance in background in
+// the reverse dependency order, each app is deleted once all of the apps depending
+// on it are gone. the instance is removed once all of the apps are gone. on failure,
+// the instance is kept in deleting state with the error message, so the operator
+// could retry with force, then the apps not gone in time are skipped.
+func (s *Scheduler) DeleteInstance(ins *types.Instance, force bool, timeout time.Duration) error {
+	order, err := ins.Order()
+	if err != nil {
+		return err
+	}
+
+	if err := s.updateInstance(ins, types.InstanceDeleting, ""); err != nil {
+		return err
+	}
+
+	go s.deleteServices(ins, order, force, timeout)
+	return nil
+}
+
+func (s *Scheduler) deleteServices(ins *types.Instance, order []*types.Service, force bool, timeout time.Duration) {
+	for i := len(order) - 1; i >= 0; i-- {
+		svc := order[i]
+
+		if err := s.deleteService(svc, force, timeout); err != nil {
+			err = fmt.Errorf("delete app of service %s error: %v", svc.Name, err)
+			if !force {
+				s.updateInstance(ins, types.InstanceDeleting, err.Error()+", retry with force")
+				return
+			}
+			log.Warnf("force deleting compose instance %s: %v", ins.ID, err)
+		}
+	}
+
+	if err := store.DB().DeleteInstance(ins.ID); err != nil {
+		log.Errorf("remove compose instance %s error: %v", ins.ID, err)
+		s.updateInstance(ins, types.InstanceDeleting, err.Error())
+		return
+	}
+
+	log.Printf("compose instance %s deleted", ins.ID)
+	s.emit(&types.Event{
+		ID:     ins.ID,
+		Status: "deleted",
+		From:   types.InstanceDeleting,
+		Time:   time.Now(),
+	})
+}
+
+// deleteService delete the service's app and wait until it's gone
+func (s *Scheduler) deleteService(svc *types.Service, force bool, timeout time.Duration) error {
+	app, err := store.DB().GetApp(svc.AppID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.DeleteApp(app, force, timeout); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout + updateKillTimeout)
+	for {
+		_, err := store.DB().GetApp(svc.AppID)
+		if store.IsNotFound(err) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not deleted in %s", timeout)
+		}
+		time.Sleep(updatePollInterval)
+	}
+}
+
+// updateInstance persist the compose instance with the new status
+// and emit an event on changed.
+func (s *Scheduler) updateInstance(ins *types.Instance, status, errmsg string) error {
+	prev := ins.Status
+	ins.Status = status
+	ins.ErrMsg = errmsg
+	ins.UpdatedAt = time.Now().UnixNano()
+
+	if err := store.DB().UpdateInstance(ins); err != nil {
+		log.Errorf("update compose instance %s error: %v", ins.ID, err)
+		return err
+	}
+
+	if prev != status {
+		s.emit(&types.Event{
+			ID:      ins.ID,
+			Status:  status,
+			From:    prev,
+			Time:    time.Now(),
+			Message: errmsg,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	defaultHistoryLimit = 3      // default nb of runs retained per cron job
+	maxMissedRuns       = 100000 // max nb of missed schedules to skip over at a time
+)
+
+var (
+	// ErrCronJobRunning represents the run is forbidden as the previous run is still running
+	ErrCronJobRunning = errors.New("previous run of the cron job is still running")
+)
+
+// CreateCronJob persist the cron job, it's runs are started by the schedule
+// from now on.
+func (s *Scheduler) CreateCronJob(cj *types.CronJob) error {
+	sched, err := types.ParseCron(cj.Schedule)
+	if err != nil {
+		return err
+	}
+	loc, err := cj.Location()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cj.CreatedAt = now.UnixNano()
+	cj.NextScheduleTime = nextScheduleTime(sched, now.In(loc))
+	return store.DB().CreateCronJob(cj)
+}
+
+// nextScheduleTime return the next fire time after t in unix nano, or 0 if never fires
+func nextScheduleTime(sched *types.CronSchedule, t time.Time) int64 {
+	next := sched.Next(t)
+	if next.IsZero() {
+		return 0
+	}
+	return next.UnixNano()
+}
+
+// DeleteCronJob delete all of the run apps of the cron job, and remove the cron job
+func (s *Scheduler) DeleteCronJob(cj *types.CronJob, timeout time.Duration) error {
+	s.cronMu.Lock()
+	defer s.cronMu.Unlock()
+
+	for _, run := range cj.Runs {
+		if run.AppID == "" {
+			continue
+		}
+		app, err := store.DB().GetApp(run.AppID)
+		if err != nil {
+			continue
+		}
+		if err := s.DeleteApp(app, true, timeout); err != nil {
+			log.Errorf("delete app %s of cron job %s error: %v", app.ID, cj.ID, err)
+		}
+	}
+
+	return store.DB().DeleteCronJob(cj.ID)
+}
+
+// RunCronJob start a run of the cron job now regardless of the schedule,
+// the concurrency policy still applies.
+func (s *Scheduler) RunCronJob(id string) (*types.CronRun, error) {
+	s.cronMu.Lock()
+	defer s.cronMu.Unlock()
+
+	cj, err := store.DB().GetCronJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	run := s.startRun(cj, time.Now(), true)
+	s.gcRuns(cj)
+	if err := store.DB().UpdateCronJob(cj); err != nil {
+		return nil, err
+	}
+
+	switch run.Status {
+	case types.CronRunSkipped:
+		return run, ErrCronJobRunning
+	case types.CronRunFailed:
+		return run, errors.New(run.Message)
+	}
+	return run, nil
+}
+
+// fireCronJobs start the runs of the cron jobs which are due. only the latest of the
+// schedules due since the last run is started, the earlier ones are skipped over.
+// the scheduled time of the last run is persisted, so the runs are not repeated by
+// the restarted manager.
+func (s *Scheduler) fireCronJobs() {
+	cjs, err := store.DB().ListCronJobs()
+	if err != nil {
+		log.Errorf("list cron jobs error: %v", err)
+		return
+	}
+
+	for _, cj := range cjs {
+		s.cronMu.Lock()
+		if cur, err := store.DB().GetCronJob(cj.ID); err == nil {
+			s.fireCronJob(cur, time.Now())
+		}
+		s.cronMu.Unlock()
+	}
+}
+
+// fireCronJob start the run of the cron job if due
+// NOTE the caller should hold the cronMu
+func (s *Scheduler) fireCronJob(cj *types.CronJob, now time.Time) {
+	sched, err := types.ParseCron(cj.Schedule)
+	if err != nil {
+		return
+	}
+	loc, err := cj.Location()
+	if err != nil {
+		return
+	}
+
+	last := cj.LastScheduleTime
+	if last == 0 {
+		last = cj.CreatedAt
+	}
+
+	var due time.Time
+	t := time.Unix(0, last).In(loc)
+	for i := 0; i < maxMissedRuns; i++ {
+		if t = sched.Next(t); t.IsZero() || t.After(now) {
+			break
+		}
+		due = t
+	}
+
+	next := nextScheduleTime(sched, now.In(loc))
+	if due.IsZero() && next == cj.NextScheduleTime {
+		return // not due yet
+	}
+	cj.NextScheduleTime = next
+
+	if !due.IsZero() {
+		cj.LastScheduleTime = due.UnixNano()
+
+		dl := time.Duration(cj.StartingDeadlineSeconds) * time.Second
+		if dl > 0 && now.Sub(due) > dl {
+			log.Warnf("cron job %s missed the run scheduled at %v", cj.ID, due)
+			cj.Runs = append(cj.Runs, &types.CronRun{
+				ScheduledAt: due.UnixNano(),
+				Status:      types.CronRunMissed,
+				Message:     fmt.Sprintf("not started in %s", dl),
+			})
+		} else {
+			s.startRun(cj, due, false)
+		}
+		s.gcRuns(cj)
+	}
+
+	if err := store.DB().UpdateCronJob(cj); err != nil {
+		log.Errorf("update cron job %s error: %v", cj.ID, err)
+	}
+}
+
+// startRun create the job app of the run by the concurrency policy,
+// and record the run into the cron job.
+// NOTE the caller should hold the cronMu
+func (s *Scheduler) startRun(cj *types.CronJob, scheduled time.Time, manual bool) *types.CronRun {
+	run := &types.CronRun{
+		ScheduledAt: scheduled.UnixNano(),
+		Manual:      manual,
+	}
+	cj.Runs = append(cj.Runs, run)
+
+	active := activeRuns(cj)
+	switch cj.ConcurrencyPolicy {
+	case types.ConcurrencyForbid:
+		if len(active) > 0 {
+			run.Status = types.CronRunSkipped
+			run.Message = fmt.Sprintf("run %s is still running", active[0].ID)
+			log.Printf("cron job %s: %s, skipped", cj.ID, run.Message)
+			return run
+		}
+	case types.ConcurrencyReplace:
+		for _, app := range active {
+			log.Printf("cron job %s: replacing the running run %s", cj.ID, app.ID)
+			if err := s.DeleteApp(app, true, updateKillTimeout); err != nil {
+				log.Errorf("delete app %s of cron job %s error: %v", app.ID, cj.ID, err)
+			}
+		}
+	}
+
+	// deep copied, the run's app should not share anything with the template
+	ver, err := cj.Template.Copy()
+	if err != nil {
+		run.Status = types.CronRunFailed
+		run.Message = err.Error()
+		log.Errorf("cron job %s: copy template error: %v", cj.ID, err)
+		return run
+	}
+	ver.AppName = cj.RunName(scheduled, manual)
+	ver.RunAs = cj.RunAs
+
+	app := NewApp(cj.ClusterID, ver)
+	if _, err := store.DB().GetApp(app.ID); err == nil {
+		run.Status = types.CronRunFailed
+		run.Message = fmt.Sprintf("app %s already exists", app.ID)
+		return run
+	}
+	if err := s.CreateApp(app); err != nil {
+		run.Status = types.CronRunFailed
+		run.Message = err.Error()
+		log.Errorf("cron job %s: create app %s error: %v", cj.ID, app.ID, err)
+		return run
+	}
+
+	run.AppID = app.ID
+	run.StartedAt = time.Now().UnixNano()
+	run.Status = types.CronRunStarted
+	log.Printf("cron job %s: run %s started", cj.ID, app.ID)
+
+	s.emit(&types.Event{
+		ID:     cj.ID,
+		Status: "run",
+		Time:   time.Now(),
+	})
+	return run
+}
+
+// gcRuns forget the oldest runs beyond the history limit and delete their apps,
+// the running runs are kept, and the others are trimmed around them.
+// NOTE the caller should hold the cronMu
+func (s *Scheduler) gcRuns(cj *types.CronJob) {
+	limit := cj.HistoryLimit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	var (
+		excess = len(cj.Runs) - limit
+		kept   = make([]*types.CronRun, 0, len(cj.Runs))
+	)
+	for _, run := range cj.Runs {
+		if excess <= 0 {
+			kept = append(kept, run)
+			continue
+		}
+		if run.AppID != "" {
+			app, err := store.DB().GetApp(run.AppID)
+			if err == nil && app.State != types.AppDeleting {
+				if app.Job != nil && app.Job.Status == types.JobRunning {
+					kept = append(kept, run)
+					continue
+				}
+				if err := s.DeleteApp(app, false, updateKillTimeout); err != nil {
+					log.Errorf("delete app %s of cron job %s error: %v", app.ID, cj.ID, err)
+				}
+			}
+		}
+		excess--
+	}
+	cj.Runs = kept
+}
+
+// activeRuns return the apps of the cron job's runs which are still running
+func activeRuns(cj *types.CronJob) []*types.App {
+	ret := make([]*types.App, 0)
+	for _, run := range cj.Runs {
+		if run.AppID == "" {
+			continue
+		}
+		app, err := store.DB().GetApp(run.AppID)
+		if err != nil || app.State == types.AppDeleting {
+			continue
+		}
+		if app.Job != nil && app.Job.Status == types.JobRunning {
+			ret = append(ret, app)
+		}
+	}
+	return ret
+}
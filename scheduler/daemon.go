@@ -0,0 +1,309 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// daemonAgents return the known agents matching the daemon app's constraints,
+// mapped to whether the new tasks could be placed on them. the draining and
+// drained agents are excluded, their daemon tasks are killed without migration.
+// NOTE the caller should hold the lock
+func (s *Scheduler) daemonAgents(ver *types.AppVersion) map[string]bool {
+	ret := make(map[string]bool)
+	for id, a := range s.agents {
+		if err := matchConstraints(a, ver.Constraints); err != nil {
+			continue
+		}
+		if m, ok := s.maint[id]; ok && (m.State == types.AgentDraining || m.State == types.AgentDrained) {
+			continue
+		}
+		ret[id] = s.schedulable(id) == nil
+	}
+	return ret
+}
+
+// syncDaemons keep one task of each daemon app on every matching agent
+func (s *Scheduler) syncDaemons() {
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		log.Errorf("sync daemon apps error: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if app.Version != nil && app.Version.IsDaemon() {
+			s.syncDaemon(app)
+		}
+	}
+}
+
+// syncDaemon launch the daemon app's tasks on the matching agents without one,
+// and kill the tasks on the known agents which stop matching, or the duplicated
+// ones. the tasks on the agents not known yet (eg: just failed over) are kept.
+// the desired instances of the app is the nb of agents hosting it's tasks.
+func (s *Scheduler) syncDaemon(app *types.App) {
+	if app.State != types.AppCreating && app.State != types.AppNormal {
+		return // suspended, failed, or being updated by others
+	}
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		log.Errorf("list tasks of app %s error: %v", app.ID, err)
+		return
+	}
+
+	s.Lock()
+	if _, ok := s.updates[app.ID]; ok {
+		s.Unlock()
+		return
+	}
+
+	var (
+		agents  = s.daemonAgents(app.Version)
+		covered = make(map[string]bool) // agents hosting an alive or pending task
+		victims = make([]*types.Task, 0)
+		stale   = make([]string, 0)
+	)
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; ok || !isAlive(t.State) {
+			continue
+		}
+		_, known := s.agents[t.AgentID]
+		_, matched := agents[t.AgentID]
+		if (known && !matched) || covered[t.AgentID] {
+			victims = append(victims, t)
+			continue
+		}
+		covered[t.AgentID] = true
+	}
+	for _, p := range s.queue.list() {
+		if p.AppID != app.ID {
+			continue
+		}
+		if launchable := agents[p.AgentID]; !launchable || covered[p.AgentID] {
+			stale = append(stale, p.TaskID)
+			continue
+		}
+		covered[p.AgentID] = true
+	}
+	for _, id := range stale {
+		s.queue.remove(id)
+	}
+
+	var launched int
+	for id, launchable := range agents {
+		if launchable && !covered[id] {
+			p := s.pushOn(app.ID, app.Version, id, 0)
+			log.Printf("launching daemon task %s of app %s on agent %s", p.TaskID, app.ID, id)
+			covered[id] = true
+			launched++
+		}
+	}
+	s.Unlock()
+
+	for _, t := range victims {
+		log.Printf("killing daemon task %s of app %s on agent %s", t.ID, app.ID, t.AgentID)
+		if err := s.KillTask(t, app.Version.KillPolicy, false); err != nil {
+			log.Errorf("kill task %s error: %v", t.ID, err)
+		}
+	}
+
+	if n := int32(len(covered)); n != app.Version.Instances {
+		if err := s.setDaemonInstances(app.ID, app.Version.ID, n); err != nil {
+			log.Errorf("update instances of app %s error: %v", app.ID, err)
+			return
+		}
+	}
+
+	if launched > 0 {
+		s.reviveIfNeeded()
+		s.schedule()
+	}
+	s.checkAppReady(app.ID)
+}
+
+// setDaemonInstances persist the derived instances of the daemon app. the app is
+// reloaded so the changes made by the others since listed are kept, and left as
+// it is if the version changed meanwhile.
+func (s *Scheduler) setDaemonInstances(appID, verID string, n int32) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	app, err := store.DB().GetApp(appID)
+	if err != nil {
+		return err
+	}
+	if app.Version == nil || app.Version.ID != verID || app.Version.Instances == n {
+		return nil
+	}
+
+	log.Printf("daemon app %s instances changed: %d -> %d", app.ID, app.Version.Instances, n)
+	app.Version.Instances = n
+	app.UpdatedAt = time.Now().UnixNano()
+	return store.DB().UpdateApp(app)
+}
+
+// relaunchDaemon relaunch the gone task of the daemon app on the same agent if the
+// agent still matches and hosts no other task of the app.
+// it returns the task id of the replacement, or empty if not replaced.
+func (s *Scheduler) relaunchDaemon(app *types.App, task *types.Task, delay time.Duration) string {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return ""
+	}
+
+	s.Lock()
+	if !s.daemonAgents(app.Version)[task.AgentID] {
+		s.Unlock()
+		return ""
+	}
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; !ok && isAlive(t.State) && t.AgentID == task.AgentID {
+			s.Unlock()
+			return ""
+		}
+	}
+	for _, p := range s.queue.list() {
+		if p.AppID == app.ID && p.AgentID == task.AgentID {
+			s.Unlock()
+			return ""
+		}
+	}
+	p := s.pushOn(app.ID, app.Version, task.AgentID, delay)
+	s.Unlock()
+
+	log.Printf("task %s gone with %s, relaunching on agent %s after %s", task.ID, task.State, task.AgentID, delay)
+	s.reviveIfNeeded()
+	s.schedule()
+	return p.TaskID
+}
+
+// pushOn put a new instance of the app version pinned to the agent into the launch queue
+// NOTE the caller should hold the lock
+func (s *Scheduler) pushOn(appID string, ver *types.AppVersion, agentID string, delay time.Duration) *Pending {
+	p := s.push(appID, ver, 1, delay)[0]
+	p.AgentID = agentID
+	return p
+}
+
+// pushNewOn put a new task of the update pinned to the agent into the launch queue
+// NOTE the caller should hold the lock
+func (s *Scheduler) pushNewOn(appID string, u *update, agentID string) string {
+	p := s.pushOn(appID, u.to, agentID, 0)
+	p.weight = u.weight
+	u.tasks[p.TaskID] = true
+	u.pinned[p.TaskID] = agentID
+	return p.TaskID
+}
+
+// rollDaemon replace the daemon app's tasks step by step on the same agents. as one
+// agent hosts only one task of the app, the old tasks are killed before the new ones
+// launched, at most `Step` agents are unavailable at a time. the newly matching
+// agents are filled up by syncDaemons once updated.
+func (s *Scheduler) rollDaemon(app *types.App, u *update) error {
+	var (
+		step       = updateStep(u.to)
+		delay      time.Duration
+		maxRetries int
+	)
+	if p := u.to.UpdatePolicy; p != nil {
+		delay = time.Duration(p.UpdateDelay) * time.Second
+		maxRetries = int(p.MaxRetries)
+	}
+
+	killTimeout := updateKillTimeout
+	if p := u.from.KillPolicy; p != nil {
+		killTimeout += time.Duration(p.Duration) * time.Second
+	}
+
+	for {
+		_, olds, err := s.splitTasks(app.ID, u)
+		if err != nil {
+			return err
+		}
+		if err := s.saveProgress(app, u, olds); err != nil {
+			return err
+		}
+
+		if len(olds) == 0 {
+			return nil
+		}
+
+		batch := olds[:minInt(step, len(olds))]
+		if err := s.KillAndWait(batch, u.from.KillPolicy, false, killTimeout); err != nil {
+			return err
+		}
+
+		// not relaunched on the agents which stop matching the new version
+		s.Lock()
+		agents := s.daemonAgents(u.to)
+		ids := make([]string, 0, len(batch))
+		for _, t := range batch {
+			if agents[t.AgentID] {
+				ids = append(ids, s.pushNewOn(app.ID, u, t.AgentID))
+			}
+		}
+		s.Unlock()
+
+		s.reviveIfNeeded()
+		s.schedule()
+
+		if err := s.waitReady(app, u, ids, maxRetries); err != nil {
+			return err
+		}
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// dryRunDaemon evaluate the placement of the daemon app version on each of
+// the agents matching it's constraints.
+// NOTE the caller should hold the lock
+func (s *Scheduler) dryRunDaemon(ver *types.AppVersion, nodes []*node) *types.DryRunResult {
+	agents := s.daemonAgents(ver)
+
+	hosts := make([]*types.Agent, 0, len(agents))
+	for id := range agents {
+		hosts = append(hosts, &types.Agent{ID: id, Hostname: s.agents[id].hostname})
+	}
+	sort.Sort(agentSorter(hosts))
+
+	ret := &types.DryRunResult{
+		Instances:  len(hosts),
+		Placements: make([]*types.InstancePlacement, 0, len(hosts)),
+	}
+	for i, h := range hosts {
+		pl := &types.InstancePlacement{
+			Index:    i,
+			Failures: make(map[string]string),
+		}
+		ret.Placements = append(ret.Placements, pl)
+
+		n := nodeOf(nodes, h.ID)
+		switch {
+		case n == nil:
+			pl.Failures[h.ID] = "no offers cached from the agent"
+		case !agents[h.ID]:
+			pl.Failures[h.ID] = s.schedulable(h.ID).Error()
+		default:
+			if err := n.fit(ver); err != nil {
+				pl.Failures[h.ID] = err.Error()
+				continue
+			}
+			n.consume(ver)
+			pl.Candidates = []string{h.Hostname}
+			pl.Chosen = h.Hostname
+			ret.Placeable++
+		}
+	}
+
+	return ret
+}
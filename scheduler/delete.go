@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// DeleteApp gracefully delete the app in background: kill all of it's tasks honoring
+// the KillPolicy, wait until all of them gone, then remove the app with all of it's
+// versions & tasks. with force, the tasks are killed without grace period, and the app
+// is removed even if some of the tasks are not confirmed gone before timeout.
+func (s *Scheduler) DeleteApp(app *types.App, force bool, timeout time.Duration) error {
+	if err := s.updateAppState(app, types.AppDeleting, func(a *types.App) { a.ErrMsg = "" }); err != nil {
+		return err
+	}
+
+	go s.deleteApp(app, force, timeout)
+	return nil
+}
+
+func (s *Scheduler) deleteApp(app *types.App, force bool, timeout time.Duration) {
+	s.AbortUpdate(app.ID)
+	s.Dequeue(app.ID)
+	s.ResetBackoff(app.ID)
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		s.deleteFailed(app, err)
+		return
+	}
+
+	policy := app.Version.KillPolicy
+	if force {
+		policy = nil
+	}
+
+	log.Printf("deleting app %s, killing %d tasks", app.ID, len(tasks))
+	if err := s.KillAndWait(tasks, policy, false, timeout); err != nil {
+		if !force {
+			s.deleteFailed(app, err)
+			return
+		}
+		log.Warnf("force deleting app %s: %v", app.ID, err)
+	}
+
+	if err := store.DB().DeleteApp(app.ID); err != nil {
+		s.deleteFailed(app, err)
+		return
+	}
+
+	log.Printf("app %s deleted", app.ID)
+	s.emit(&types.Event{
+		ID:     app.ID,
+		Status: "deleted",
+		From:   app.State,
+		Time:   time.Now(),
+	})
+}
+
+// deleteFailed keep the app in deleting state with the error message,
+// so the operator could retry the deletion with force.
+func (s *Scheduler) deleteFailed(app *types.App, err error) {
+	log.Errorf("delete app %s error: %v", app.ID, err)
+	s.updateAppState(app, types.AppDeleting, func(a *types.App) {
+		a.ErrMsg = fmt.Sprintf("delete app error: %v, retry with force", err)
+	})
+}
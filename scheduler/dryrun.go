@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/bbklab/swan-ng/types"
+)
+
+// DryRun evaluate the placement of all instances of the app version against the
+// currently cached offers, without launching anything. the instances are placed
+// one by one in the same way as the real scheduling, so later instances see the
+// resources consumed by the earlier ones.
+func (s *Scheduler) DryRun(ver *types.AppVersion) *types.DryRunResult {
+	s.Lock()
+	defer s.Unlock()
+
+	var (
+		nodes = buildNodes(s.offers, s.agents)
+		ret   = &types.DryRunResult{
+			Instances:  int(ver.Instances),
+			Placements: make([]*types.InstancePlacement, 0, ver.Instances),
+		}
+	)
+
+	if ver.IsDaemon() {
+		return s.dryRunDaemon(ver, nodes)
+	}
+
+	// agents known but without any cached offers
+	idle := make(map[string]string)
+	for id := range s.agents {
+		if nodeOf(nodes, id) == nil {
+			idle[id] = "no offers cached from the agent"
+		}
+	}
+
+	for i := 0; i < int(ver.Instances); i++ {
+		pl := &types.InstancePlacement{
+			Index:    i,
+			Failures: make(map[string]string),
+		}
+		for id, reason := range idle {
+			pl.Failures[id] = reason
+		}
+
+		var chosen *node
+		for _, n := range nodes {
+			if err := s.fit(n, ver); err != nil {
+				pl.Failures[n.agent.id] = err.Error()
+				continue
+			}
+			pl.Candidates = append(pl.Candidates, n.agent.hostname)
+			if chosen == nil {
+				chosen = n
+			}
+		}
+
+		if chosen != nil {
+			chosen.consume(ver)
+			pl.Chosen = chosen.agent.hostname
+			ret.Placeable++
+			sort.Sort(nodeSorter(nodes))
+		}
+
+		ret.Placements = append(ret.Placements, pl)
+	}
+
+	return ret
+}
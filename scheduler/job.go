@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+var (
+	// ErrJobApp represents the operation is not supported by the job apps
+	ErrJobApp = errors.New("operation not supported by job app")
+)
+
+// jobPolicy return the job policy of the version with defaults filled
+func jobPolicy(ver *types.AppVersion) *types.JobPolicy {
+	p := types.JobPolicy{}
+	if ver.Job != nil {
+		p = *ver.Job
+	}
+	if p.Completions <= 0 {
+		p.Completions = 1
+	}
+	if p.Parallelism <= 0 {
+		p.Parallelism = 1
+	}
+	return &p
+}
+
+// notJob return ErrJobApp if the app is a job
+func notJob(app *types.App) error {
+	if app.Version != nil && app.Version.IsJob() {
+		return ErrJobApp
+	}
+	return nil
+}
+
+// jobTaskGone count the gone task of the job app, and launch the tasks required
+// to complete the job. the finished tasks are never relaunched, the tasks killed
+// by us with requeue (eg: force drained) are retried without counting as failures.
+// it returns the task id of the retry if the gone task failed.
+func (s *Scheduler) jobTaskGone(app *types.App, task *types.Task, killed bool) string {
+	s.jobMu.Lock()
+	defer s.jobMu.Unlock()
+
+	app, err := store.DB().GetApp(app.ID)
+	if err != nil {
+		return ""
+	}
+
+	st := app.Job
+	if st == nil || st.Status != types.JobRunning {
+		return ""
+	}
+
+	var delay time.Duration
+	switch {
+	case task.State == "TASK_FINISHED":
+		st.Succeeded++
+		log.Printf("job %s: task %s finished, %d succeeded", app.ID, task.ID, st.Succeeded)
+	case killed:
+	default:
+		st.Failed++
+		st.Message = fmt.Sprintf("task %s gone with %s: %s", task.ID, task.State, task.Message)
+		log.Printf("job %s: %s", app.ID, st.Message)
+		delay = (&backoff{failures: int(st.Failed)}).delay(s.cfg.BackoffBase, s.cfg.BackoffMax)
+	}
+
+	p := jobPolicy(app.Version)
+	switch {
+	case st.Succeeded >= p.Completions:
+		s.finishJob(app, types.JobSucceeded, "")
+		return ""
+	case st.Failed > p.BackoffLimit:
+		s.finishJob(app, types.JobFailed, fmt.Sprintf("backoff limit %d exceeded, last failure: %s", p.BackoffLimit, st.Message))
+		return ""
+	}
+
+	if err := s.updateAppState(app, app.State, func(a *types.App) { a.Job = st }); err != nil {
+		return ""
+	}
+
+	n := minInt(int(p.Parallelism), int(p.Completions-st.Succeeded)) - s.activeTasks(app.ID)
+	if n <= 0 {
+		return ""
+	}
+
+	ids := s.enqueue(app, n, delay)
+	if task.State == "TASK_FINISHED" {
+		return ""
+	}
+	return ids[0]
+}
+
+// finishJob mark the job succeeded or failed, and kill the rest of it's tasks
+// NOTE the caller should hold the jobMu
+func (s *Scheduler) finishJob(app *types.App, status, msg string) {
+	st := app.Job
+	st.Status = status
+	st.CompletedAt = time.Now().UnixNano()
+	if msg != "" {
+		st.Message = msg
+	}
+
+	state := types.AppNormal
+	if status == types.JobFailed {
+		state = types.AppFailed
+	}
+	if err := s.updateAppState(app, state, func(a *types.App) {
+		a.Job = st
+		if status == types.JobFailed {
+			a.ErrMsg = msg
+		}
+	}); err != nil {
+		return
+	}
+	log.Printf("job %s %s", app.ID, status)
+
+	s.Dequeue(app.ID)
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		log.Errorf("list tasks of job %s error: %v", app.ID, err)
+		return
+	}
+	for _, t := range tasks {
+		if !isAlive(t.State) {
+			continue
+		}
+		if err := s.KillTask(t, app.Version.KillPolicy, false); err != nil {
+			log.Errorf("kill task %s of job %s error: %v", t.ID, app.ID, err)
+		}
+	}
+}
+
+// checkJobDeadlines fail the running jobs beyond their active deadlines
+func (s *Scheduler) checkJobDeadlines() {
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		return
+	}
+
+	for _, app := range apps {
+		if app.Version == nil || !app.Version.IsJob() || app.Job == nil || app.Job.Status != types.JobRunning {
+			continue
+		}
+
+		secs := jobPolicy(app.Version).ActiveDeadlineSeconds
+		if secs <= 0 || time.Since(time.Unix(0, app.Job.StartedAt)) < time.Duration(secs)*time.Second {
+			continue
+		}
+
+		s.jobMu.Lock()
+		if cur, err := store.DB().GetApp(app.ID); err == nil && cur.Job != nil && cur.Job.Status == types.JobRunning {
+			s.finishJob(cur, types.JobFailed, fmt.Sprintf("active deadline %ds exceeded", secs))
+		}
+		s.jobMu.Unlock()
+	}
+}
+
+// activeTasks return the nb of the app's alive and pending tasks which are not being killed
+func (s *Scheduler) activeTasks(appID string) int {
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return 0
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	n := s.queue.countApp(appID)
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; !ok && isAlive(t.State) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,281 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bbklab/swan-ng/mesos/protobuf/mesos"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// offer represents a cached mesos offer
+type offer struct {
+	id         string
+	agentID    string
+	hostname   string
+	cpus       float64
+	mem        float64
+	disk       float64
+	ports      []uint64
+	receivedAt time.Time
+}
+
+func newOffer(o *mesos.Offer) *offer {
+	ret := &offer{
+		id:         o.GetId().GetValue(),
+		agentID:    o.GetAgentId().GetValue(),
+		hostname:   o.GetHostname(),
+		receivedAt: time.Now(),
+	}
+
+	for _, res := range o.GetResources() {
+		switch res.GetName() {
+		case "cpus":
+			ret.cpus += res.GetScalar().GetValue()
+		case "mem":
+			ret.mem += res.GetScalar().GetValue()
+		case "disk":
+			ret.disk += res.GetScalar().GetValue()
+		case "ports":
+			for _, r := range res.GetRanges().GetRange() {
+				for p := r.GetBegin(); p <= r.GetEnd(); p++ {
+					ret.ports = append(ret.ports, p)
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// agent represents a mesos agent which ever sent offers to us
+type agent struct {
+	id       string
+	hostname string
+	attrs    map[string]string
+}
+
+func newAgent(o *mesos.Offer) *agent {
+	ret := &agent{
+		id:       o.GetAgentId().GetValue(),
+		hostname: o.GetHostname(),
+		attrs:    make(map[string]string),
+	}
+
+	for _, attr := range o.GetAttributes() {
+		var val string
+		switch attr.GetType() {
+		case mesos.Value_SCALAR:
+			val = strconv.FormatFloat(attr.GetScalar().GetValue(), 'f', -1, 64)
+		case mesos.Value_TEXT:
+			val = attr.GetText().GetValue()
+		case mesos.Value_SET:
+			val = fmt.Sprintf("%v", attr.GetSet().GetItem())
+		case mesos.Value_RANGES:
+			val = fmt.Sprintf("%v", attr.GetRanges().GetRange())
+		}
+		ret.attrs[attr.GetName()] = val
+	}
+
+	return ret
+}
+
+// lookup return the value of the agent's specified field which used by constraints
+func (a *agent) lookup(field string) (string, bool) {
+	switch field {
+	case "hostname":
+		return a.hostname, true
+	case "agentid":
+		return a.id, true
+	}
+	val, ok := a.attrs[field]
+	return val, ok
+}
+
+// node is a per-agent aggregated view of all of cached offers on the same agent,
+// it tracks the remaining resources during a scheduling pass.
+type node struct {
+	agent    *agent
+	offerIDs []string
+	cpus     float64
+	mem      float64
+	disk     float64
+	ports    []uint64
+
+	launches []*launch // tasks placed on this node in current pass
+}
+
+// launch represents a pending task placed on a node
+type launch struct {
+	pending *Pending
+	info    *mesos.TaskInfo
+	task    *types.Task
+}
+
+// buildNodes aggregate the cached offers by agent
+func buildNodes(offers map[string]*offer, agents map[string]*agent) []*node {
+	m := make(map[string]*node)
+	for _, o := range offers {
+		n, ok := m[o.agentID]
+		if !ok {
+			a, ok := agents[o.agentID]
+			if !ok {
+				a = &agent{id: o.agentID, hostname: o.hostname, attrs: map[string]string{}}
+			}
+			n = &node{agent: a}
+			m[o.agentID] = n
+		}
+		n.offerIDs = append(n.offerIDs, o.id)
+		n.cpus += o.cpus
+		n.mem += o.mem
+		n.disk += o.disk
+		n.ports = append(n.ports, o.ports...)
+	}
+
+	ret := make([]*node, 0, len(m))
+	for _, n := range m {
+		ret = append(ret, n)
+	}
+	sort.Sort(nodeSorter(ret))
+	return ret
+}
+
+// fit check if the node could host one instance of the app version,
+// return the unplaceable reason if not.
+func (n *node) fit(ver *types.AppVersion) error {
+	if err := matchConstraints(n.agent, ver.Constraints); err != nil {
+		return err
+	}
+
+	if n.cpus < ver.Cpus {
+		return fmt.Errorf("insufficient cpus: need %.2f, offered %.2f", ver.Cpus, n.cpus)
+	}
+	if n.mem < ver.Mem {
+		return fmt.Errorf("insufficient mem: need %.2f, offered %.2f", ver.Mem, n.mem)
+	}
+	if n.disk < ver.Disk {
+		return fmt.Errorf("insufficient disk: need %.2f, offered %.2f", ver.Disk, n.disk)
+	}
+
+	var (
+		fixed   = hostPorts(ver)
+		dynamic = dynamicPorts(ver)
+		free    = len(n.ports)
+	)
+	for _, p := range fixed {
+		if !n.hasPort(p) {
+			return fmt.Errorf("host port %d unavailable", p)
+		}
+		free--
+	}
+	if free < dynamic {
+		return fmt.Errorf("insufficient ports: need %d, offered %d", dynamic+len(fixed), len(n.ports))
+	}
+
+	return nil
+}
+
+// consume subtract the app version's resources from the node,
+// return the allocated host ports in the order of port mappings.
+// NOTE the caller should make sure the node fits the app version.
+func (n *node) consume(ver *types.AppVersion) []uint64 {
+	n.cpus -= ver.Cpus
+	n.mem -= ver.Mem
+	n.disk -= ver.Disk
+
+	// take all of the fixed ports first, so the dynamic ones won't occupy them
+	for _, p := range hostPorts(ver) {
+		n.takePort(p)
+	}
+
+	ret := make([]uint64, 0)
+	for _, pm := range portMappings(ver) {
+		if pm.HostPort > 0 {
+			ret = append(ret, uint64(pm.HostPort))
+			continue
+		}
+		if len(n.ports) > 0 {
+			ret = append(ret, n.ports[0])
+			n.ports = n.ports[1:]
+		}
+	}
+
+	return ret
+}
+
+func (n *node) hasPort(p uint64) bool {
+	for _, v := range n.ports {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *node) takePort(p uint64) {
+	for i, v := range n.ports {
+		if v == p {
+			n.ports = append(n.ports[:i], n.ports[i+1:]...)
+			return
+		}
+	}
+}
+
+func matchConstraints(a *agent, expr string) error {
+	cons, err := types.ParseConstraints(expr)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cons {
+		val, ok := a.lookup(c.Field)
+		if !c.Match(val, ok) {
+			return fmt.Errorf("constraint %s unmatched", c)
+		}
+	}
+
+	return nil
+}
+
+func portMappings(ver *types.AppVersion) []*types.PortMapping {
+	if ver.Container == nil || ver.Container.Docker == nil {
+		return nil
+	}
+	return ver.Container.Docker.PortMappings
+}
+
+// hostPorts return the fixed host ports required by the app version
+func hostPorts(ver *types.AppVersion) []uint64 {
+	ret := make([]uint64, 0)
+	for _, pm := range portMappings(ver) {
+		if pm.HostPort > 0 {
+			ret = append(ret, uint64(pm.HostPort))
+		}
+	}
+	return ret
+}
+
+// dynamicPorts return the nb of dynamic host ports required by the app version
+func dynamicPorts(ver *types.AppVersion) int {
+	var n int
+	for _, pm := range portMappings(ver) {
+		if pm.HostPort == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// nodeSorter sort nodes by remaining cpus & mem desc, to spread the tasks
+type nodeSorter []*node
+
+func (s nodeSorter) Len() int      { return len(s) }
+func (s nodeSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nodeSorter) Less(i, j int) bool {
+	if s[i].cpus != s[j].cpus {
+		return s[i].cpus > s[j].cpus
+	}
+	return s[i].mem > s[j].mem
+}
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	preemptRetryInterval = 30 * time.Second // min interval of preempting for the same pending task
+)
+
+// victim represents a running task which could be preempted
+type victim struct {
+	task     *types.Task
+	priority int32
+	version  *types.AppVersion
+}
+
+// preemptable check if the unplaceable pending task is going to preempt the lower priority tasks
+// NOTE the caller should hold the lock
+func (s *Scheduler) preemptable(p *Pending) bool {
+	return s.cfg.Preemption && p.Priority > 0 && time.Since(p.preemptedAt) >= preemptRetryInterval
+}
+
+// preempt try to make room for each of the unplaceable pending tasks by killing the
+// lower priority tasks on a single agent, the killed tasks will be requeued.
+// the agent requires the least victims wins. the offered resources of the agent are
+// counted once, the ones left by the earlier preemptions are counted for the later.
+// NOTE the host ports are not taken into account, the caller should NOT hold the lock
+func (s *Scheduler) preempt(ps []*Pending, nodes []*node) {
+	var max int32
+	for _, p := range ps {
+		if p.Priority > max {
+			max = p.Priority
+		}
+	}
+
+	candidates, err := s.victimsByAgent(max)
+	if err != nil {
+		log.Errorf("collect preemption victims error: %v", err)
+		return
+	}
+
+	// the resources available on each agent, claimed by the preemptions in turn
+	avail := make(map[string]*node)
+	for agentID := range candidates {
+		n := &node{}
+		if cached := nodeOf(nodes, agentID); cached != nil {
+			n.cpus, n.mem, n.disk = cached.cpus, cached.mem, cached.disk
+		}
+		avail[agentID] = n
+	}
+
+	s.Lock()
+	kills := make([]*victim, 0)
+	for _, p := range ps {
+		var (
+			best      []*victim
+			bestAgent *agent
+		)
+		for agentID, all := range candidates {
+			if p.AgentID != "" && agentID != p.AgentID {
+				continue
+			}
+			a, ok := s.agents[agentID]
+			if !ok {
+				continue
+			}
+			if err := s.schedulable(agentID); err != nil {
+				continue
+			}
+			if err := matchConstraints(a, p.version.Constraints); err != nil {
+				continue
+			}
+
+			// skip the victims being killed, including the ones chosen for the others
+			vs := make([]*victim, 0, len(all))
+			for _, v := range all {
+				if _, ok := s.killing[v.task.ID]; !ok && v.priority < p.Priority {
+					vs = append(vs, v)
+				}
+			}
+
+			chosen := pickVictims(p.version, avail[agentID], vs)
+			if chosen == nil {
+				continue
+			}
+			if best == nil || len(chosen) < len(best) {
+				best, bestAgent = chosen, a
+			}
+		}
+
+		if best == nil {
+			continue
+		}
+
+		n := avail[bestAgent.id]
+		for _, v := range best {
+			log.Printf("preempting task %s (priority %d) for %s (priority %d)", v.task.ID, v.priority, p.TaskID, p.Priority)
+			s.killing[v.task.ID] = &killing{requeue: true}
+			kills = append(kills, v)
+			n.cpus += v.version.Cpus
+			n.mem += v.version.Mem
+			n.disk += v.version.Disk
+		}
+		n.cpus -= p.version.Cpus
+		n.mem -= p.version.Mem
+		n.disk -= p.version.Disk
+
+		p.preemptedAt = time.Now()
+		p.Reason = fmt.Sprintf("preempting %d lower priority tasks on %s", len(best), bestAgent.hostname)
+	}
+	s.Unlock()
+
+	for _, v := range kills {
+		if err := s.sendKill(v.task, v.version.KillPolicy); err != nil {
+			log.Errorf("kill task %s error: %v", v.task.ID, err)
+		}
+	}
+}
+
+// victimsByAgent collect all of alive tasks with lower priority, grouped by agent.
+// the tasks being killed are filtered out by the caller with the lock held.
+func (s *Scheduler) victimsByAgent(priority int32) (map[string][]*victim, error) {
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]*victim)
+	for _, app := range apps {
+		if app.Version == nil || app.Version.Priority >= priority {
+			continue
+		}
+
+		tasks, err := store.DB().ListTasks(app.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tasks {
+			if !isAlive(t.State) || t.State == "TASK_KILLING" {
+				continue
+			}
+			ret[t.AgentID] = append(ret[t.AgentID], &victim{
+				task:     t,
+				priority: app.Version.Priority,
+				version:  app.Version,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+// pickVictims choose the least victims (lowest priority & newest first) on the
+// agent to free enough resources, return nil if it's impossible.
+func pickVictims(ver *types.AppVersion, n *node, vs []*victim) []*victim {
+	sort.Sort(victimSorter(vs))
+
+	var cpus, mem, disk float64
+	if n != nil {
+		cpus, mem, disk = n.cpus, n.mem, n.disk
+	}
+
+	ret := make([]*victim, 0)
+	for _, v := range vs {
+		if cpus >= ver.Cpus && mem >= ver.Mem && disk >= ver.Disk {
+			break
+		}
+		cpus += v.version.Cpus
+		mem += v.version.Mem
+		disk += v.version.Disk
+		ret = append(ret, v)
+	}
+
+	if cpus < ver.Cpus || mem < ver.Mem || disk < ver.Disk {
+		return nil
+	}
+	return ret
+}
+
+func nodeOf(nodes []*node, agentID string) *node {
+	for _, n := range nodes {
+		if n.agent.id == agentID {
+			return n
+		}
+	}
+	return nil
+}
+
+// victimSorter sort victims by priority asc, then by created time desc
+type victimSorter []*victim
+
+func (s victimSorter) Len() int      { return len(s) }
+func (s victimSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s victimSorter) Less(i, j int) bool {
+	if s[i].priority != s[j].priority {
+		return s[i].priority < s[j].priority
+	}
+	return s[i].task.CreatedAt > s[j].task.CreatedAt
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bbklab/swan-ng/types"
+)
+
+// Pending represents a task waiting in the launch queue
+type Pending struct {
+	TaskID     string    `json:"taskId"`
+	AppID      string    `json:"appId"`
+	AgentID    string    `json:"agentId,omitempty"` // the agent the task is pinned to, only for daemon apps
+	Priority   int32     `json:"priority"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	WaitTime   string    `json:"waitTime"`
+	Reason     string    `json:"lastUnplaceableReason,omitempty"`
+
+	version     *types.AppVersion // the app settings to launch with
+	weight      float64           // traffic weight to launch with
+	preemptedAt time.Time         // the last time we preempted tasks for it
+	notBefore   time.Time         // launch backoff, won't be launched before it
+}
+
+// launchQueue holds all of pending tasks, ordered by priority & age
+// NOTE not concurrency safe, protected by the scheduler's lock
+type launchQueue struct {
+	items []*Pending
+}
+
+func newLaunchQueue() *launchQueue {
+	return &launchQueue{
+		items: make([]*Pending, 0),
+	}
+}
+
+func (q *launchQueue) push(p *Pending) {
+	q.items = append(q.items, p)
+	sort.Stable(pendingSorter(q.items))
+}
+
+func (q *launchQueue) remove(taskID string) *Pending {
+	for i, p := range q.items {
+		if p.TaskID == taskID {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return p
+		}
+	}
+	return nil
+}
+
+// removeApp remove all of pending tasks of the app
+func (q *launchQueue) removeApp(appID string) int {
+	var (
+		n    int
+		kept = make([]*Pending, 0, len(q.items))
+	)
+	for _, p := range q.items {
+		if p.AppID == appID {
+			n++
+			continue
+		}
+		kept = append(kept, p)
+	}
+	q.items = kept
+	return n
+}
+
+// removeNewest remove at most n of the app's newest pending tasks,
+// return the nb of removed pending tasks.
+func (q *launchQueue) removeNewest(appID string, n int) int {
+	var removed int
+	for i := len(q.items) - 1; i >= 0 && removed < n; i-- {
+		if q.items[i].AppID != appID {
+			continue
+		}
+		q.items = append(q.items[:i], q.items[i+1:]...)
+		removed++
+	}
+	return removed
+}
+
+// countApp return the nb of pending tasks of the app
+func (q *launchQueue) countApp(appID string) int {
+	var n int
+	for _, p := range q.items {
+		if p.AppID == appID {
+			n++
+		}
+	}
+	return n
+}
+
+func (q *launchQueue) len() int {
+	return len(q.items)
+}
+
+// list return the ordered pending tasks
+func (q *launchQueue) list() []*Pending {
+	return q.items[:]
+}
+
+// snapshot return copies of the pending tasks for displaying
+func (q *launchQueue) snapshot() []*Pending {
+	ret := make([]*Pending, 0, len(q.items))
+	for _, p := range q.items {
+		cp := *p
+		cp.WaitTime = time.Since(p.EnqueuedAt).String()
+		ret = append(ret, &cp)
+	}
+	return ret
+}
+
+// pendingSorter sort pending tasks by priority desc, then by enqueued time asc
+type pendingSorter []*Pending
+
+func (s pendingSorter) Len() int      { return len(s) }
+func (s pendingSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s pendingSorter) Less(i, j int) bool {
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority > s[j].Priority
+	}
+	return s[i].EnqueuedAt.Before(s[j].EnqueuedAt)
+}
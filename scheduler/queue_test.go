@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbklab/swan-ng/types"
+)
+
+func TestLaunchQueueOrder(t *testing.T) {
+	var (
+		q   = newLaunchQueue()
+		now = time.Now()
+	)
+
+	q.push(&Pending{TaskID: "batch-old", Priority: 0, EnqueuedAt: now.Add(-time.Hour)})
+	q.push(&Pending{TaskID: "critical-new", Priority: 100, EnqueuedAt: now})
+	q.push(&Pending{TaskID: "critical-old", Priority: 100, EnqueuedAt: now.Add(-time.Minute)})
+	q.push(&Pending{TaskID: "normal", Priority: 10, EnqueuedAt: now.Add(-time.Hour)})
+
+	expect := []string{"critical-old", "critical-new", "normal", "batch-old"}
+	for i, p := range q.list() {
+		if p.TaskID != expect[i] {
+			t.Fatalf("position %d: expect %s, got %s", i, expect[i], p.TaskID)
+		}
+	}
+
+	if p := q.remove("normal"); p == nil || q.len() != 3 {
+		t.Fatalf("remove pending task failed")
+	}
+}
+
+func TestPickVictims(t *testing.T) {
+	var (
+		ver = &types.AppVersion{Cpus: 2, Mem: 256}
+		low = &types.AppVersion{Cpus: 1, Mem: 128}
+		mid = &types.AppVersion{Cpus: 1, Mem: 128}
+		vs  = []*victim{
+			{task: &types.Task{ID: "mid"}, priority: 5, version: mid},
+			{task: &types.Task{ID: "low-old", CreatedAt: 1}, priority: 1, version: low},
+			{task: &types.Task{ID: "low-new", CreatedAt: 2}, priority: 1, version: low},
+		}
+	)
+
+	// half of the resources are available on the node
+	chosen := pickVictims(ver, &node{cpus: 1, mem: 128}, vs)
+	if len(chosen) != 1 || chosen[0].task.ID != "low-new" {
+		t.Fatalf("expect to preempt [low-new], got %v", chosen)
+	}
+
+	// nothing available on the node
+	chosen = pickVictims(ver, nil, vs)
+	if len(chosen) != 2 || chosen[0].task.ID != "low-new" || chosen[1].task.ID != "low-old" {
+		t.Fatalf("expect to preempt [low-new low-old], got %v", chosen)
+	}
+
+	// impossible
+	if chosen = pickVictims(&types.AppVersion{Cpus: 10}, nil, vs); chosen != nil {
+		t.Fatalf("expect no victims, got %v", chosen)
+	}
+}
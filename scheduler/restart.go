@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// RestartApp replace the app's tasks batch by batch with the same version, the
+// same way as rolling update driven by the app's UpdatePolicy. optionally only
+// the tasks on the agent or the unhealthy tasks are restarted.
+// it returns the nb of tasks to be restarted.
+func (s *Scheduler) RestartApp(app *types.App, agentID string, unhealthyOnly bool) (int, error) {
+	if err := notJob(app); err != nil {
+		return 0, err
+	}
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	selected := make(map[string]bool)
+	s.Lock()
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; ok || !isAlive(t.State) {
+			continue
+		}
+		if agentID != "" && t.AgentID != agentID {
+			continue
+		}
+		if unhealthyOnly && t.Healthy != "unhealthy" {
+			continue
+		}
+		selected[t.ID] = true
+	}
+	s.Unlock()
+
+	if len(selected) == 0 {
+		return 0, nil
+	}
+
+	u := newUpdate(app.Version, app.Version)
+	u.strategy = "restart"
+	u.total = len(selected)
+	u.selected = selected
+
+	s.Lock()
+	if _, ok := s.updates[app.ID]; ok {
+		s.Unlock()
+		return 0, ErrUpdating
+	}
+	s.updates[app.ID] = u
+	s.Unlock()
+
+	progress := &types.UpdateProgress{
+		Strategy:  u.strategy,
+		Total:     u.total,
+		Step:      updateStep(app.Version),
+		StartedAt: time.Now().UnixNano(),
+	}
+	if err := s.updateAppState(app, types.AppUpdating, func(a *types.App) {
+		a.ErrMsg = ""
+		a.Progress = progress
+	}); err != nil {
+		s.AbortUpdate(app.ID)
+		return 0, err
+	}
+
+	log.Printf("restarting %d tasks of app %s", u.total, app.ID)
+	go s.restart(app, u)
+	return u.total, nil
+}
+
+func (s *Scheduler) restart(app *types.App, u *update) {
+	err := s.roll(app, u)
+	if s.finishUpdate(app.ID, u, nil) {
+		return
+	}
+
+	if err != nil {
+		log.Errorf("restart app %s error: %v", app.ID, err)
+		s.updateAppState(app, types.AppFailed, func(a *types.App) {
+			a.Progress = nil
+			a.ErrMsg = fmt.Sprintf("restart failed: %v, stopped", err)
+		})
+	} else {
+		log.Printf("app %s restarted", app.ID)
+		s.updateAppState(app, types.AppNormal, func(a *types.App) { a.Progress = nil })
+	}
+
+	// the other tasks died while restarting are not relaunched
+	s.fillUp(app)
+}
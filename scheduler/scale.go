@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// victim selection policies on scaling down
+const (
+	VictimNewest    = "newest"    // the newest tasks first
+	VictimUnhealthy = "unhealthy" // the not running or unhealthy tasks first
+	VictimCrowded   = "crowded"   // the tasks on the agent hosting most of the app's tasks first
+)
+
+// ValidVictimPolicy check if the scale down victim selection policy is supported
+func ValidVictimPolicy(policy string) bool {
+	switch policy {
+	case "", VictimNewest, VictimUnhealthy, VictimCrowded:
+		return true
+	}
+	return false
+}
+
+// ScaleApp scale the app to the desired nb of instances. on scaling up, the new
+// instances are queued for launching; on scaling down, the pending instances are
+// dropped first, then the running victims chosen by the policy are killed
+// honoring the KillPolicy.
+func (s *Scheduler) ScaleApp(app *types.App, instances int, policy string, timeout time.Duration) error {
+	if err := notJob(app); err != nil {
+		return err
+	}
+
+	s.scaleMu.Lock()
+	defer s.scaleMu.Unlock()
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	alive := make([]*types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; ok || !isAlive(t.State) {
+			continue
+		}
+		alive = append(alive, t)
+	}
+	pending := s.queue.countApp(app.ID)
+	s.Unlock()
+
+	from := app.Version.Instances
+	if err := s.updateAppState(app, types.AppScaling, func(a *types.App) {
+		a.Version.Instances = int32(instances)
+	}); err != nil {
+		return err
+	}
+	log.Printf("scaling app %s from %d to %d instances", app.ID, from, instances)
+
+	current := len(alive) + pending
+	switch {
+	case instances > current:
+		s.Enqueue(app, instances-current)
+
+	case instances < current:
+		n := current - instances
+
+		s.Lock()
+		n -= s.queue.removeNewest(app.ID, n)
+		s.Unlock()
+
+		if n > 0 {
+			victims := selectVictims(alive, n, policy)
+			go func() {
+				if err := s.KillAndWait(victims, app.Version.KillPolicy, false, timeout); err != nil {
+					log.Errorf("scale down app %s error: %v", app.ID, err)
+				}
+				s.checkAppReady(app.ID)
+			}()
+			return nil
+		}
+	}
+
+	s.checkAppReady(app.ID)
+	return nil
+}
+
+// ScaleDownTask kill the specified task without replacement and decrease the
+// app's desired instances by one.
+func (s *Scheduler) ScaleDownTask(app *types.App, task *types.Task, timeout time.Duration) error {
+	if err := notJob(app); err != nil {
+		return err
+	}
+
+	s.scaleMu.Lock()
+	defer s.scaleMu.Unlock()
+
+	if err := s.updateAppState(app, types.AppScaling, func(a *types.App) {
+		if a.Version.Instances > 0 {
+			a.Version.Instances--
+		}
+	}); err != nil {
+		return err
+	}
+	log.Printf("scaling app %s down to %d instances by killing task %s", app.ID, app.Version.Instances, task.ID)
+
+	go func() {
+		if err := s.KillAndWait([]*types.Task{task}, app.Version.KillPolicy, false, timeout); err != nil {
+			log.Errorf("scale down app %s error: %v", app.ID, err)
+		}
+		s.checkAppReady(app.ID)
+	}()
+	return nil
+}
+
+// selectVictims choose n victims from the tasks by the policy
+func selectVictims(tasks []*types.Task, n int, policy string) []*types.Task {
+	if n >= len(tasks) {
+		return tasks
+	}
+
+	cands := make([]*types.Task, len(tasks))
+	copy(cands, tasks)
+
+	switch policy {
+	case VictimUnhealthy:
+		sort.Sort(unhealthyFirst(cands))
+		return cands[:n]
+
+	case VictimCrowded:
+		sort.Sort(newestFirst(cands))
+		counts := make(map[string]int)
+		for _, t := range cands {
+			counts[t.AgentID]++
+		}
+
+		ret := make([]*types.Task, 0, n)
+		for len(ret) < n {
+			var crowded string
+			for agent, c := range counts {
+				if c > counts[crowded] || (c == counts[crowded] && agent < crowded) {
+					crowded = agent
+				}
+			}
+			for i, t := range cands {
+				if t.AgentID == crowded {
+					ret = append(ret, t)
+					cands = append(cands[:i], cands[i+1:]...)
+					break
+				}
+			}
+			counts[crowded]--
+		}
+		return ret
+
+	default:
+		sort.Sort(newestFirst(cands))
+		return cands[:n]
+	}
+}
+
+// newestFirst sort tasks by created time desc
+type newestFirst []*types.Task
+
+func (s newestFirst) Len() int           { return len(s) }
+func (s newestFirst) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s newestFirst) Less(i, j int) bool { return s[i].CreatedAt > s[j].CreatedAt }
+
+// unhealthyFirst sort tasks by: not running, unhealthy, others, then by created time desc
+type unhealthyFirst []*types.Task
+
+func (s unhealthyFirst) Len() int      { return len(s) }
+func (s unhealthyFirst) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s unhealthyFirst) Less(i, j int) bool {
+	if ri, rj := healthRank(s[i]), healthRank(s[j]); ri != rj {
+		return ri < rj
+	}
+	return s[i].CreatedAt > s[j].CreatedAt
+}
+
+func healthRank(t *types.Task) int {
+	switch {
+	case t.State != "TASK_RUNNING":
+		return 0
+	case t.Healthy == "unhealthy":
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,603 @@
+// Package scheduler ...
+// scheduler.go implements the mesos framework scheduler which consumes the
+// mesos events, caches the offers and places the pending tasks on to them.
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/mesos"
+	mesosproto "github.com/bbklab/swan-ng/mesos/protobuf/mesos"
+	"github.com/bbklab/swan-ng/mesos/protobuf/sched"
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	offerHoldTimeout = 10 * time.Second // decline the cached offers after held so long
+	offerRefuseSecs  = 5                // refuse seconds for the declined offers
+	tickInterval     = 5 * time.Second  // interval of the periodical scheduling
+	maxReasons       = 3                // max nb of per-agent unplaceable reasons to keep
+	maxTaskHistories = 10               // max nb of previous runs kept per task
+)
+
+// Scheduler represents the swan mesos framework scheduler
+type Scheduler struct {
+	sync.Mutex // protect offers, agents, maint, queue, killing, waiters, backoffs, updates
+
+	stateMu sync.Mutex // serialize the app state transitions
+	scaleMu sync.Mutex // serialize the app scalings
+	jobMu   sync.Mutex // serialize the job status changes
+	cronMu  sync.Mutex // serialize the cron job runs
+
+	cfg  *types.MgrConfig
+	cli  *mesos.Client
+	emit func(*types.Event) error
+
+	offers   map[string]*offer                  // offer id -> cached offer
+	agents   map[string]*agent                  // agent id -> agent which sent offers, forgotten once failed
+	maint    map[string]*types.AgentMaintenance // agent id -> agent under maintenance
+	queue    *launchQueue                       // pending tasks waiting for launching
+	killing  map[string]*killing                // task id -> the task being killed by us
+	waiters  map[string][]chan struct{}         // task id -> waiters notified once the task gone
+	backoffs map[string]*backoff                // app id -> relaunch backoff of the app's failing tasks
+	updates  map[string]*update                 // app id -> the ongoing update of the app
+}
+
+// killing represents a task being killed by us
+type killing struct {
+	requeue bool // launch a replacement after the task gone
+}
+
+// New ...
+func New(cfg *types.MgrConfig, cli *mesos.Client, emit func(*types.Event) error) *Scheduler {
+	return &Scheduler{
+		cfg:      cfg,
+		cli:      cli,
+		emit:     emit,
+		offers:   make(map[string]*offer),
+		agents:   make(map[string]*agent),
+		maint:    make(map[string]*types.AgentMaintenance),
+		queue:    newLaunchQueue(),
+		killing:  make(map[string]*killing),
+		waiters:  make(map[string][]chan struct{}),
+		backoffs: make(map[string]*backoff),
+		updates:  make(map[string]*update),
+	}
+}
+
+// Start startup the mesos events consumer & the periodical scheduling loop
+func (s *Scheduler) Start() error {
+	if err := s.loadAgents(); err != nil {
+		return fmt.Errorf("load agents maintenance states error: %v", err)
+	}
+
+	if err := s.failInterruptedUpdates(); err != nil {
+		return fmt.Errorf("fail the interrupted updates error: %v", err)
+	}
+
+	if err := s.resumeInstances(); err != nil {
+		return fmt.Errorf("resume the interrupted compose instances error: %v", err)
+	}
+
+	go s.watchEvents()
+	go s.loop()
+	return nil
+}
+
+func (s *Scheduler) watchEvents() {
+	for {
+		select {
+		case ev := <-s.cli.Events():
+			s.handleEvent(ev)
+		case err := <-s.cli.Errors():
+			log.Errorf("mesos events subscriber error: %v", err)
+		}
+	}
+}
+
+func (s *Scheduler) loop() {
+	for range time.Tick(tickInterval) {
+		s.declineStaleOffers()
+		s.resetStableBackoffs()
+		s.drainAgents()
+		s.checkJobDeadlines()
+		s.fireCronJobs()
+		s.syncDaemons()
+		s.schedule()
+		s.reviveIfNeeded()
+	}
+}
+
+func (s *Scheduler) handleEvent(ev *sched.Event) {
+	switch ev.GetType() {
+	case sched.Event_SUBSCRIBED:
+		id := ev.GetSubscribed().GetFrameworkId().GetValue()
+		log.Printf("subscribed with framework id: %s", id)
+		s.cli.SetFrameworkID(id)
+		if err := store.DB().UpdateFrameworkID(id); err != nil {
+			log.Errorf("save framework id %s error: %v", id, err)
+		}
+
+	case sched.Event_OFFERS:
+		s.addOffers(ev.GetOffers().GetOffers())
+		s.schedule()
+
+	case sched.Event_RESCIND:
+		s.Lock()
+		delete(s.offers, ev.GetRescind().GetOfferId().GetValue())
+		s.Unlock()
+
+	case sched.Event_UPDATE:
+		s.handleUpdate(ev.GetUpdate().GetStatus())
+
+	case sched.Event_FAILURE:
+		// the executor failures carry the agent id as well
+		if id := ev.GetFailure().GetAgentId().GetValue(); id != "" && ev.GetFailure().GetExecutorId() == nil {
+			log.Warnf("mesos agent %s failure", id)
+			s.removeAgent(id)
+		}
+
+	case sched.Event_ERROR:
+		log.Errorf("mesos error event: %s", ev.GetError().GetMessage())
+	}
+}
+
+func (s *Scheduler) addOffers(offers []*mesosproto.Offer) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, o := range offers {
+		s.offers[o.GetId().GetValue()] = newOffer(o)
+		s.agents[o.GetAgentId().GetValue()] = newAgent(o)
+	}
+}
+
+// removeAgent forget the failed agent and it's cached offers
+func (s *Scheduler) removeAgent(agentID string) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.agents, agentID)
+	for id, o := range s.offers {
+		if o.agentID == agentID {
+			delete(s.offers, id)
+		}
+	}
+}
+
+// declineStaleOffers decline the offers which held too long, so that
+// other frameworks get the chance to use them.
+func (s *Scheduler) declineStaleOffers() {
+	s.Lock()
+	defer s.Unlock()
+
+	ids := make([]*mesosproto.OfferID, 0)
+	for id, o := range s.offers {
+		if time.Since(o.receivedAt) < offerHoldTimeout {
+			continue
+		}
+		ids = append(ids, &mesosproto.OfferID{Value: &o.id})
+		delete(s.offers, id)
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	if err := s.cli.Decline(ids, offerRefuseSecs); err != nil {
+		log.Errorf("decline %d offers error: %v", len(ids), err)
+	}
+}
+
+// reviveIfNeeded ask mesos for offers again if we have pending tasks but no offers
+func (s *Scheduler) reviveIfNeeded() {
+	s.Lock()
+	need := s.queue.len() > 0 && len(s.offers) == 0
+	s.Unlock()
+
+	if !need {
+		return
+	}
+
+	if err := s.cli.Revive(); err != nil {
+		log.Errorf("revive offers error: %v", err)
+	}
+}
+
+// Enqueue put `n` new instances of the app into the launch queue,
+// return the task ids of the pending tasks.
+func (s *Scheduler) Enqueue(app *types.App, n int) []string {
+	return s.enqueue(app, n, 0)
+}
+
+// enqueue put `n` new instances of the app into the launch queue,
+// which won't be launched until the delay passed.
+func (s *Scheduler) enqueue(app *types.App, n int, delay time.Duration) []string {
+	s.Lock()
+	ids := make([]string, 0, n)
+	for _, p := range s.push(app.ID, app.Version, n, delay) {
+		ids = append(ids, p.TaskID)
+	}
+	s.Unlock()
+
+	s.reviveIfNeeded()
+	s.schedule()
+	return ids
+}
+
+// push put `n` new instances of the app version into the launch queue
+// NOTE the caller should hold the lock
+func (s *Scheduler) push(appID string, ver *types.AppVersion, n int, delay time.Duration) []*Pending {
+	ret := make([]*Pending, 0, n)
+	for i := 0; i < n; i++ {
+		p := &Pending{
+			TaskID:     newTaskID(appID),
+			AppID:      appID,
+			Priority:   ver.Priority,
+			EnqueuedAt: time.Now(),
+			version:    ver,
+			weight:     defaultTaskWeight,
+			notBefore:  time.Now().Add(delay),
+		}
+		s.queue.push(p)
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// Dequeue remove all of the app's pending tasks from the launch queue
+func (s *Scheduler) Dequeue(appID string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.queue.removeApp(appID)
+}
+
+// Queue return the ordered pending tasks in the launch queue
+func (s *Scheduler) Queue() []*Pending {
+	s.Lock()
+	defer s.Unlock()
+	return s.queue.snapshot()
+}
+
+// schedule try to place the pending tasks on to the cached offers,
+// pending tasks with higher priority are placed first.
+func (s *Scheduler) schedule() {
+	s.Lock()
+
+	if s.queue.len() == 0 {
+		s.Unlock()
+		return
+	}
+
+	var (
+		nodes    = buildNodes(s.offers, s.agents)
+		preempts = make([]*Pending, 0)
+	)
+
+	for _, p := range s.queue.list() {
+		if time.Now().Before(p.notBefore) {
+			p.Reason = fmt.Sprintf("backing off, next launch after %s", p.notBefore.Format(time.RFC3339))
+			continue
+		}
+
+		n, reason := s.place(p, nodes)
+		if n == nil {
+			p.Reason = reason
+			if s.preemptable(p) {
+				preempts = append(preempts, p)
+			}
+			continue
+		}
+
+		ports := n.consume(p.version)
+		info, task := buildTask(p, n, ports)
+		n.launches = append(n.launches, &launch{pending: p, info: info, task: task})
+		sort.Sort(nodeSorter(nodes))
+	}
+
+	for _, n := range nodes {
+		if len(n.launches) > 0 {
+			s.launch(n)
+		}
+	}
+	s.Unlock()
+
+	// the store scanning and the killing are out of the lock
+	if len(preempts) > 0 {
+		s.preempt(preempts, nodes)
+	}
+}
+
+// place find the first node which fits the pending task, or the unplaceable reason
+func (s *Scheduler) place(p *Pending, nodes []*node) (*node, string) {
+	if len(nodes) == 0 {
+		return nil, "no offers available"
+	}
+
+	if p.AgentID != "" {
+		n := nodeOf(nodes, p.AgentID)
+		if n == nil {
+			return nil, fmt.Sprintf("no offers available from the pinned agent %s", p.AgentID)
+		}
+		if err := s.fit(n, p.version); err != nil {
+			return nil, fmt.Sprintf("%s: %v", n.agent.hostname, err)
+		}
+		return n, ""
+	}
+
+	reasons := make([]string, 0, maxReasons)
+	for _, n := range nodes {
+		err := s.fit(n, p.version)
+		if err == nil {
+			return n, ""
+		}
+		if len(reasons) < maxReasons {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", n.agent.hostname, err))
+		}
+	}
+
+	if more := len(nodes) - len(reasons); more > 0 {
+		reasons = append(reasons, fmt.Sprintf("and %d more agents", more))
+	}
+	return nil, strings.Join(reasons, "; ")
+}
+
+// launch accept all of the node's offers and launch the tasks placed on it
+// NOTE the caller should hold the lock
+func (s *Scheduler) launch(n *node) {
+	var (
+		offerIDs = make([]*mesosproto.OfferID, 0, len(n.offerIDs))
+		infos    = make([]*mesosproto.TaskInfo, 0, len(n.launches))
+		saved    = make([]*launch, 0, len(n.launches))
+	)
+
+	for _, id := range n.offerIDs {
+		id := id
+		offerIDs = append(offerIDs, &mesosproto.OfferID{Value: &id})
+		delete(s.offers, id) // the offers are consumed anyway
+	}
+
+	for _, l := range n.launches {
+		if err := store.DB().UpdateTask(l.pending.AppID, l.task); err != nil {
+			log.Errorf("save task %s error: %v", l.task.ID, err)
+			l.pending.Reason = fmt.Sprintf("save task error: %v", err)
+			continue
+		}
+		infos = append(infos, l.info)
+		saved = append(saved, l)
+	}
+
+	if len(infos) == 0 {
+		return
+	}
+
+	if err := s.cli.Launch(offerIDs, infos); err != nil {
+		log.Errorf("launch %d tasks on %s error: %v", len(infos), n.agent.hostname, err)
+		for _, l := range saved {
+			store.DB().DeleteTask(l.pending.AppID, l.task.ID)
+			l.pending.Reason = fmt.Sprintf("launch error: %v", err)
+		}
+		return
+	}
+
+	for _, l := range saved {
+		s.queue.remove(l.pending.TaskID)
+		log.Printf("launched task %s on %s", l.task.ID, n.agent.hostname)
+	}
+}
+
+// handleUpdate sync the task status into the store
+func (s *Scheduler) handleUpdate(status *mesosproto.TaskStatus) {
+	var (
+		tid   = status.GetTaskId().GetValue()
+		aid   = appIDOf(tid)
+		state = status.GetState().String()
+	)
+
+	if len(status.GetUuid()) > 0 {
+		if err := s.cli.Acknowledge(status); err != nil {
+			log.Errorf("acknowledge task %s status %s error: %v", tid, state, err)
+		}
+	}
+
+	task, err := store.DB().GetTask(aid, tid)
+	if err != nil {
+		if store.IsNotFound(err) && isAlive(state) {
+			log.Warnf("kill unknown task %s", tid)
+			s.cli.Kill(tid, status.GetAgentId().GetValue(), 0)
+		}
+		return
+	}
+
+	prev := task.State
+	task.State = state
+	task.Message = status.GetMessage()
+	if status.Reason != nil {
+		task.Reason = status.GetReason().String()
+	}
+	if status.Healthy != nil {
+		task.Healthy = "unhealthy"
+		if status.GetHealthy() {
+			task.Healthy = "healthy"
+		}
+	}
+	if cid := status.GetContainerStatus().GetContainerId().GetValue(); cid != "" {
+		task.ContainerID = cid
+	}
+
+	if prev != state {
+		s.emit(&types.Event{
+			ID:     tid,
+			Status: state,
+			From:   prev,
+			Time:   time.Now(),
+		})
+	}
+
+	if !isTerminal(state) {
+		if err := store.DB().UpdateTask(aid, task); err != nil {
+			log.Errorf("update task %s error: %v", tid, err)
+		}
+		s.markRunning(aid, state == "TASK_RUNNING" && task.Healthy != "unhealthy")
+		if state == "TASK_RUNNING" {
+			s.checkAppReady(aid)
+		}
+		return
+	}
+
+	s.taskGone(task)
+}
+
+// taskGone cleanup the terminated task and launch a replacement if required
+func (s *Scheduler) taskGone(task *types.Task) {
+	s.Lock()
+	k, killed := s.killing[task.ID]
+	delete(s.killing, task.ID)
+	for _, ch := range s.waiters[task.ID] {
+		close(ch)
+	}
+	delete(s.waiters, task.ID)
+	s.Unlock()
+
+	if err := store.DB().DeleteTask(task.AppID, task.ID); err != nil {
+		log.Errorf("remove task %s error: %v", task.ID, err)
+	}
+
+	requeue := !killed || k.requeue
+	s.archive(task, s.replace(task, killed, requeue))
+}
+
+// replace launch a replacement of the gone task if required,
+// it returns the task id of the replacement, or empty if not replaced.
+func (s *Scheduler) replace(task *types.Task, killed, requeue bool) string {
+	// the ongoing update launches the tasks itself
+	if s.updateTaskGone(task, killed) {
+		return ""
+	}
+
+	if !requeue {
+		return ""
+	}
+
+	app, err := store.DB().GetApp(task.AppID)
+	if err != nil {
+		return "" // the app has been removed
+	}
+
+	if app.State == types.AppDeleting || app.State == types.AppSuspended {
+		return ""
+	}
+
+	if app.Version.IsJob() {
+		return s.jobTaskGone(app, task, killed)
+	}
+
+	var delay time.Duration
+	if !killed { // the task died unexpectedly
+		var relaunch bool
+		if delay, relaunch = s.recordFailure(app, task); !relaunch {
+			return ""
+		}
+	}
+
+	if app.Version.IsDaemon() {
+		return s.relaunchDaemon(app, task, delay)
+	}
+
+	log.Printf("task %s gone with %s, relaunching after %s", task.ID, task.State, delay)
+	return s.enqueue(app, 1, delay)[0]
+}
+
+// archive move the gone task with it's histories to the replacement's histories,
+// or forget them if not replaced.
+func (s *Scheduler) archive(task *types.Task, replacement string) {
+	hs, err := store.DB().GetTaskHistories(task.AppID, task.ID)
+	if err != nil {
+		log.Errorf("get histories of task %s error: %v", task.ID, err)
+	}
+
+	if replacement != "" {
+		task.ArchivedAt = time.Now().UnixNano()
+		hs = append(hs, task)
+		if len(hs) > maxTaskHistories {
+			hs = hs[len(hs)-maxTaskHistories:]
+		}
+		for _, h := range hs {
+			if err := store.DB().AddTaskHistory(task.AppID, replacement, h); err != nil {
+				log.Errorf("archive task %s error: %v", h.ID, err)
+			}
+		}
+	}
+
+	if err := store.DB().DeleteTaskHistories(task.AppID, task.ID); err != nil {
+		log.Errorf("remove histories of task %s error: %v", task.ID, err)
+	}
+}
+
+// KillTask kill the task honoring the KillPolicy, a replacement will
+// be launched after the task gone if requeue is true.
+func (s *Scheduler) KillTask(task *types.Task, policy *types.KillPolicy, requeue bool) error {
+	s.Lock()
+	s.killing[task.ID] = &killing{requeue: requeue}
+	s.Unlock()
+
+	return s.sendKill(task, policy)
+}
+
+// KillAndWait kill the tasks honoring the KillPolicy and wait until all of them
+// gone, it returns error if not all of the tasks gone before timeout.
+// the tasks already terminated are not waited.
+func (s *Scheduler) KillAndWait(tasks []*types.Task, policy *types.KillPolicy, requeue bool, timeout time.Duration) error {
+	var (
+		chs   = make([]chan struct{}, 0, len(tasks))
+		kills = make([]*types.Task, 0, len(tasks))
+	)
+
+	s.Lock()
+	for _, t := range tasks {
+		if !isAlive(t.State) {
+			continue // no more status updates to close the waiter
+		}
+		ch := make(chan struct{})
+		s.waiters[t.ID] = append(s.waiters[t.ID], ch)
+		s.killing[t.ID] = &killing{requeue: requeue}
+		chs = append(chs, ch)
+		kills = append(kills, t)
+	}
+	s.Unlock()
+
+	for _, t := range kills {
+		if err := s.sendKill(t, policy); err != nil {
+			log.Errorf("kill task %s error: %v", t.ID, err)
+		}
+	}
+
+	deadline := time.After(timeout)
+	for i, ch := range chs {
+		select {
+		case <-ch:
+		case <-deadline:
+			return fmt.Errorf("timeout after %s, %d of %d tasks not gone yet", timeout, len(chs)-i, len(chs))
+		}
+	}
+
+	return nil
+}
+
+// sendKill ask mesos to kill the task with the grace period of the KillPolicy
+// NOTE the caller should NOT hold the lock, and should have recorded the killing
+func (s *Scheduler) sendKill(task *types.Task, policy *types.KillPolicy) error {
+	var grace int64
+	if policy != nil {
+		grace = policy.Duration
+	}
+
+	return s.cli.Kill(task.ID, task.AgentID, grace)
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// SuspendApp kill all of the app's tasks honoring the KillPolicy in background,
+// the app settings and the desired instances are kept for resuming.
+func (s *Scheduler) SuspendApp(app *types.App, timeout time.Duration) error {
+	if err := notJob(app); err != nil {
+		return err
+	}
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.updateAppState(app, types.AppSuspended); err != nil {
+		return err
+	}
+
+	s.Dequeue(app.ID)
+	s.ResetBackoff(app.ID)
+
+	log.Printf("suspending app %s, killing %d tasks", app.ID, len(tasks))
+	go func() {
+		if err := s.KillAndWait(tasks, app.Version.KillPolicy, false, timeout); err != nil {
+			log.Errorf("suspend app %s error: %v", app.ID, err)
+		}
+	}()
+	return nil
+}
+
+// ResumeApp relaunch the desired instances of the suspended app
+func (s *Scheduler) ResumeApp(app *types.App) error {
+	if app.State != types.AppSuspended {
+		return &types.StateError{From: app.State, To: "resumed"}
+	}
+
+	state := types.AppCreating
+	if app.Version.Instances == 0 {
+		state = types.AppNormal
+	}
+	if err := s.updateAppState(app, state); err != nil {
+		return err
+	}
+
+	log.Printf("resuming app %s with %d instances", app.ID, app.Version.Instances)
+	s.fillUp(app)
+	return nil
+}
@@ -0,0 +1,334 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/bbklab/swan-ng/mesos/protobuf/mesos"
+	"github.com/bbklab/swan-ng/types"
+	"github.com/bbklab/swan-ng/utils"
+)
+
+const (
+	defaultTaskWeight = 100 // traffic weight of the tasks by default
+)
+
+// newTaskID generate an unique task id for the app: `{random}.{appID}`
+func newTaskID(appID string) string {
+	return fmt.Sprintf("%s.%s", utils.RandStr(6), appID)
+}
+
+// appIDOf extract the app id from the task id
+func appIDOf(taskID string) string {
+	fields := strings.SplitN(taskID, ".", 2)
+	if len(fields) != 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// isTerminal check if the task state is a terminal state.
+// NOTE TASK_UNREACHABLE is not terminal as we are partition aware.
+func isTerminal(state string) bool {
+	switch state {
+	case "TASK_FINISHED", "TASK_FAILED", "TASK_KILLED", "TASK_ERROR",
+		"TASK_LOST", "TASK_DROPPED", "TASK_GONE", "TASK_GONE_BY_OPERATOR", "TASK_UNKNOWN":
+		return true
+	}
+	return false
+}
+
+// isAlive check if the task is staging or running
+func isAlive(state string) bool {
+	switch state {
+	case "TASK_STAGING", "TASK_STARTING", "TASK_RUNNING", "TASK_KILLING", "TASK_UNREACHABLE":
+		return true
+	}
+	return false
+}
+
+// buildTask build the mesos TaskInfo and the swan task record
+// for the pending task on the node with the allocated host ports.
+func buildTask(p *Pending, n *node, ports []uint64) (*mesos.TaskInfo, *types.Task) {
+	ver := p.version
+
+	info := &mesos.TaskInfo{
+		Name:      proto.String(p.AppID),
+		TaskId:    &mesos.TaskID{Value: proto.String(p.TaskID)},
+		AgentId:   &mesos.AgentID{Value: proto.String(n.agent.id)},
+		Resources: buildResources(ver, ports),
+		Command:   buildCommand(ver, ports),
+		Container: buildContainer(ver, ports),
+		Labels:    buildLabels(ver),
+	}
+
+	if hc := buildHealthCheck(ver, ports); hc != nil {
+		info.HealthCheck = hc
+	}
+
+	if kp := ver.KillPolicy; kp != nil && kp.Duration > 0 {
+		info.KillPolicy = &mesos.KillPolicy{
+			GracePeriod: &mesos.DurationInfo{
+				Nanoseconds: proto.Int64(kp.Duration * int64(time.Second)),
+			},
+		}
+	}
+
+	task := &types.Task{
+		ID:            p.TaskID,
+		AppID:         p.AppID,
+		VersionID:     p.version.ID,
+		State:         "TASK_STAGING",
+		HostPorts:     ports,
+		AgentID:       n.agent.id,
+		IP:            n.agent.hostname,
+		AgentHostName: n.agent.hostname,
+		CreatedAt:     time.Now().UnixNano(),
+		Weight:        p.weight,
+	}
+	if len(n.offerIDs) > 0 {
+		task.OfferID = n.offerIDs[0]
+	}
+
+	return info, task
+}
+
+func scalar(name string, val float64) *mesos.Resource {
+	return &mesos.Resource{
+		Name:   proto.String(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: proto.Float64(val)},
+	}
+}
+
+func buildResources(ver *types.AppVersion, ports []uint64) []*mesos.Resource {
+	ret := make([]*mesos.Resource, 0)
+
+	if ver.Cpus > 0 {
+		ret = append(ret, scalar("cpus", ver.Cpus))
+	}
+	if ver.Mem > 0 {
+		ret = append(ret, scalar("mem", ver.Mem))
+	}
+	if ver.Disk > 0 {
+		ret = append(ret, scalar("disk", ver.Disk))
+	}
+
+	if len(ports) > 0 {
+		ranges := make([]*mesos.Value_Range, 0, len(ports))
+		for _, p := range ports {
+			ranges = append(ranges, &mesos.Value_Range{
+				Begin: proto.Uint64(p),
+				End:   proto.Uint64(p),
+			})
+		}
+		ret = append(ret, &mesos.Resource{
+			Name:   proto.String("ports"),
+			Type:   mesos.Value_RANGES.Enum(),
+			Ranges: &mesos.Value_Ranges{Range: ranges},
+		})
+	}
+
+	return ret
+}
+
+func buildCommand(ver *types.AppVersion, ports []uint64) *mesos.CommandInfo {
+	cmd := &mesos.CommandInfo{
+		Shell: proto.Bool(false),
+	}
+	if ver.Command != "" {
+		cmd.Shell = proto.Bool(true)
+		cmd.Value = proto.String(ver.Command)
+	}
+
+	vars := make([]*mesos.Environment_Variable, 0)
+	for k, v := range ver.Env {
+		vars = append(vars, &mesos.Environment_Variable{
+			Name:  proto.String(k),
+			Value: proto.String(v),
+		})
+	}
+	// expose the allocated host ports as env PORT0, PORT1 ... & PORT_{NAME}
+	for i, p := range ports {
+		vars = append(vars, &mesos.Environment_Variable{
+			Name:  proto.String(fmt.Sprintf("PORT%d", i)),
+			Value: proto.String(fmt.Sprintf("%d", p)),
+		})
+	}
+	for i, pm := range portMappings(ver) {
+		if pm.Name == "" || i >= len(ports) {
+			continue
+		}
+		vars = append(vars, &mesos.Environment_Variable{
+			Name:  proto.String("PORT_" + strings.ToUpper(pm.Name)),
+			Value: proto.String(fmt.Sprintf("%d", ports[i])),
+		})
+	}
+	cmd.Environment = &mesos.Environment{Variables: vars}
+
+	for _, uri := range ver.Uris {
+		cmd.Uris = append(cmd.Uris, &mesos.CommandInfo_URI{
+			Value: proto.String(uri),
+		})
+	}
+
+	return cmd
+}
+
+func buildContainer(ver *types.AppVersion, ports []uint64) *mesos.ContainerInfo {
+	if ver.Container == nil || ver.Container.Docker == nil {
+		return nil
+	}
+
+	var (
+		c      = ver.Container
+		docker = c.Docker
+	)
+
+	info := &mesos.ContainerInfo{
+		Type: mesos.ContainerInfo_DOCKER.Enum(),
+		Docker: &mesos.ContainerInfo_DockerInfo{
+			Image:          proto.String(docker.Image),
+			Privileged:     proto.Bool(docker.Privileged),
+			ForcePullImage: proto.Bool(docker.ForcePullImage),
+		},
+	}
+
+	network := strings.ToUpper(docker.Network)
+	switch network {
+	case "HOST":
+		info.Docker.Network = mesos.ContainerInfo_DockerInfo_HOST.Enum()
+	case "NONE":
+		info.Docker.Network = mesos.ContainerInfo_DockerInfo_NONE.Enum()
+	case "BRIDGE", "":
+		info.Docker.Network = mesos.ContainerInfo_DockerInfo_BRIDGE.Enum()
+	default: // user defined network
+		info.Docker.Network = mesos.ContainerInfo_DockerInfo_USER.Enum()
+		info.NetworkInfos = []*mesos.NetworkInfo{
+			{Name: proto.String(docker.Network)},
+		}
+	}
+
+	if network != "HOST" && network != "NONE" {
+		for i, pm := range docker.PortMappings {
+			if i >= len(ports) {
+				break
+			}
+			protocol := pm.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			info.Docker.PortMappings = append(info.Docker.PortMappings, &mesos.ContainerInfo_DockerInfo_PortMapping{
+				HostPort:      proto.Uint32(uint32(ports[i])),
+				ContainerPort: proto.Uint32(uint32(pm.ContainerPort)),
+				Protocol:      proto.String(protocol),
+			})
+		}
+	}
+
+	for _, p := range docker.Parameters {
+		info.Docker.Parameters = append(info.Docker.Parameters, &mesos.Parameter{
+			Key:   proto.String(p.Key),
+			Value: proto.String(p.Value),
+		})
+	}
+
+	for _, v := range c.Volumes {
+		mode := mesos.Volume_RW.Enum()
+		if strings.ToUpper(v.Mode) == "RO" {
+			mode = mesos.Volume_RO.Enum()
+		}
+		info.Volumes = append(info.Volumes, &mesos.Volume{
+			ContainerPath: proto.String(v.ContainerPath),
+			HostPath:      proto.String(v.HostPath),
+			Mode:          mode,
+		})
+	}
+
+	return info
+}
+
+func buildLabels(ver *types.AppVersion) *mesos.Labels {
+	labels := make([]*mesos.Label, 0, len(ver.Labels))
+	for k, v := range ver.Labels {
+		labels = append(labels, &mesos.Label{
+			Key:   proto.String(k),
+			Value: proto.String(v),
+		})
+	}
+	return &mesos.Labels{Labels: labels}
+}
+
+func buildHealthCheck(ver *types.AppVersion, ports []uint64) *mesos.HealthCheck {
+	hc := ver.HealthCheck
+	if hc == nil {
+		return nil
+	}
+
+	ret := &mesos.HealthCheck{
+		DelaySeconds:        proto.Float64(hc.DelaySeconds),
+		IntervalSeconds:     proto.Float64(hc.IntervalSeconds),
+		TimeoutSeconds:      proto.Float64(hc.TimeoutSeconds),
+		ConsecutiveFailures: proto.Uint32(hc.ConsecutiveFailures),
+		GracePeriodSeconds:  proto.Float64(hc.GracePeriodSeconds),
+	}
+
+	port := uint32(healthCheckPort(ver, ports))
+
+	switch strings.ToLower(hc.Protocol) {
+	case "http":
+		ret.Type = mesos.HealthCheck_HTTP.Enum()
+		ret.Http = &mesos.HealthCheck_HTTPCheckInfo{
+			Port: proto.Uint32(port),
+			Path: proto.String(hc.Path),
+		}
+	case "tcp":
+		ret.Type = mesos.HealthCheck_TCP.Enum()
+		ret.Tcp = &mesos.HealthCheck_TCPCheckInfo{
+			Port: proto.Uint32(port),
+		}
+	case "cmd":
+		ret.Type = mesos.HealthCheck_COMMAND.Enum()
+		ret.Command = &mesos.CommandInfo{
+			Value: proto.String(hc.Value),
+		}
+	default:
+		return nil
+	}
+
+	return ret
+}
+
+// healthCheckPort figure out the port to be checked:
+// the explicit port, or the port mapping specified by name or index.
+// the container port is used for bridge network as mesos runs the
+// checks within the container's network namespace.
+func healthCheckPort(ver *types.AppVersion, ports []uint64) int32 {
+	hc := ver.HealthCheck
+	if hc.Port > 0 {
+		return hc.Port
+	}
+
+	pms := portMappings(ver)
+	idx := int(hc.PortIndex)
+	if hc.PortName != "" {
+		for i, pm := range pms {
+			if pm.Name == hc.PortName {
+				idx = i
+				break
+			}
+		}
+	}
+	if idx < 0 || idx >= len(pms) {
+		return 0
+	}
+
+	network := strings.ToUpper(ver.Container.Docker.Network)
+	if network == "HOST" && idx < len(ports) {
+		return int32(ports[idx])
+	}
+	return pms[idx].ContainerPort
+}
@@ -0,0 +1,615 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+const (
+	updateStepTimeout  = 5 * time.Minute // max time of waiting for the new tasks of a step becoming ready
+	updateKillTimeout  = time.Minute     // max time of waiting for the old tasks gone, plus the grace period
+	updatePollInterval = time.Second
+)
+
+var (
+	// ErrUpdating represents the app has an ongoing update
+	ErrUpdating = errors.New("app is being updated")
+
+	errUpdateAborted = errors.New("update aborted")
+)
+
+// update represents an ongoing rolling update of an app
+type update struct {
+	from      *types.AppVersion // the version rolling from
+	to        *types.AppVersion // the version rolling to
+	tasks     map[string]bool   // alive or pending tasks launched with the new version
+	pinned    map[string]string // new task id -> the agent pinned to, only for daemon apps
+	total     int               // nb of the new tasks to launch
+	selected  map[string]bool   // the old tasks to replace, nil means all
+	failures  int               // nb of the new tasks died unexpectedly
+	lastError string            // the last failure of the new tasks
+	aborted   bool
+	strategy  string  // rolling, canary, bluegreen
+	weight    float64 // traffic weight of the new tasks to launch with
+	parallel  bool    // the current tasks are kept and relaunched while the new tasks launching alongside
+	adopt     bool    // the alive tasks already of the new version are counted as the new ones, eg: rolling back
+
+	soaking    bool // the canary tasks are ready and soaking
+	promote    bool // the canary is requested to be promoted
+	cancel     bool // the canary is requested to be aborted
+	switched   bool // the traffic is switched to the green tasks
+	switchBack bool // the traffic is requested to be switched back to the blue tasks
+}
+
+func newUpdate(from, to *types.AppVersion) *update {
+	return &update{
+		from:   from,
+		to:     to,
+		tasks:  make(map[string]bool),
+		pinned: make(map[string]string),
+		total:  int(to.Instances),
+		weight: defaultTaskWeight,
+	}
+}
+
+// UpdateApp start updating the app to the new version with the strategy of it's UpdatePolicy.
+//
+// by default, the app is rolling updated: at most `Step` tasks of the new version
+// are launched at a time, once they are running and healthy, the same nb of old
+// tasks are killed, until all of the instances are replaced. on failure, the
+// UpdatePolicy's Action is executed: `stop` keeps the app as it is, `rollback`
+// rolls the app back to the previous version in the same way.
+func (s *Scheduler) UpdateApp(app *types.App, ver *types.AppVersion) error {
+	return s.startUpdate(app, ver, updateStrategy(ver))
+}
+
+func (s *Scheduler) startUpdate(app *types.App, ver *types.AppVersion, strategy string) error {
+	if err := notJob(app); err != nil {
+		return err
+	}
+
+	if ver.IsDaemon() {
+		ver.Instances = app.Version.Instances // replaced on the same agents
+	}
+
+	u := newUpdate(app.Version, ver)
+	u.strategy = strategy
+
+	state := types.AppUpdating
+	switch strategy {
+	case "canary":
+		state = types.AppCanary
+		u.parallel = true
+	case "bluegreen":
+		u.parallel = true
+		u.weight = 0 // no traffic until switched
+	}
+
+	s.Lock()
+	if _, ok := s.updates[app.ID]; ok {
+		s.Unlock()
+		return ErrUpdating
+	}
+	s.updates[app.ID] = u
+	s.Unlock()
+
+	progress := &types.UpdateProgress{
+		Strategy:  strategy,
+		Total:     int(ver.Instances),
+		Step:      updateStep(ver),
+		StartedAt: time.Now().UnixNano(),
+	}
+	if err := s.updateAppState(app, state, func(a *types.App) {
+		a.ErrMsg = ""
+		a.ProposedVersion = ver
+		a.Progress = progress
+	}); err != nil {
+		s.AbortUpdate(app.ID)
+		return err
+	}
+
+	// the version attempted is recorded even if the update fails
+	if err := s.SaveVersion(app.ID, ver); err != nil {
+		log.Errorf("save version of app %s error: %v", app.ID, err)
+	}
+
+	log.Printf("updating app %s with %s strategy, %d instances", app.ID, strategy, ver.Instances)
+
+	s.Lock()
+	s.queue.removeApp(app.ID) // the pending old tasks are superseded
+	s.Unlock()
+	s.ResetBackoff(app.ID)
+
+	switch strategy {
+	case "canary":
+		go s.canaryUpdate(app, u)
+	case "bluegreen":
+		go s.blueGreenUpdate(app, u)
+	default:
+		go s.rollingUpdate(app, u)
+	}
+	return nil
+}
+
+// failInterruptedUpdates mark the apps left updating by the previous manager failed,
+// as the updates are driven in memory and could not be resumed. the proposed version
+// is kept, the operators could update or rollback the app again.
+func (s *Scheduler) failInterruptedUpdates() error {
+	apps, err := store.DB().ListApps()
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		if app.State != types.AppUpdating && app.State != types.AppCanary {
+			continue
+		}
+
+		log.Warnf("the update of app %s was interrupted by the manager restart", app.ID)
+		if err := s.updateAppState(app, types.AppFailed, func(a *types.App) {
+			a.Progress = nil
+			a.ErrMsg = "update interrupted by the manager restart, stopped"
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AbortUpdate stop the app's ongoing update if any, the launched tasks are untouched
+func (s *Scheduler) AbortUpdate(appID string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if u, ok := s.updates[appID]; ok {
+		u.aborted = true
+		delete(s.updates, appID)
+	}
+}
+
+func (s *Scheduler) rollingUpdate(app *types.App, u *update) {
+	err := s.roll(app, u)
+	if err == nil {
+		if s.finishUpdate(app.ID, u, nil) {
+			return
+		}
+		log.Printf("app %s updated", app.ID)
+		s.updateAppState(app, types.AppNormal, func(a *types.App) {
+			a.Version = u.to
+			a.ProposedVersion = nil
+			a.Progress = nil
+		})
+		return
+	}
+
+	log.Errorf("update app %s error: %v", app.ID, err)
+
+	if p := u.to.UpdatePolicy; p == nil || p.Action != "rollback" {
+		if s.finishUpdate(app.ID, u, nil) {
+			return
+		}
+		s.updateAppState(app, types.AppFailed, func(a *types.App) {
+			a.ErrMsg = fmt.Sprintf("update failed: %v, stopped", err)
+		})
+		return
+	}
+
+	rb := newUpdate(u.to, u.from)
+	rb.strategy = "rolling"
+	rb.adopt = true // the old tasks not replaced yet are left alone
+	if s.finishUpdate(app.ID, u, rb) {
+		return
+	}
+
+	log.Printf("rolling back app %s", app.ID)
+	progress := &types.UpdateProgress{
+		Strategy:  "rolling",
+		Total:     int(rb.to.Instances),
+		Step:      updateStep(rb.to),
+		StartedAt: time.Now().UnixNano(),
+	}
+	s.updateAppState(app, types.AppUpdating, func(a *types.App) {
+		a.ErrMsg = fmt.Sprintf("update failed: %v, rolling back", err)
+		a.Progress = progress
+	})
+
+	rerr := s.roll(app, rb)
+	if s.finishUpdate(app.ID, rb, nil) {
+		return
+	}
+	if rerr != nil {
+		log.Errorf("rollback app %s error: %v", app.ID, rerr)
+		s.updateAppState(app, types.AppFailed, func(a *types.App) {
+			a.ErrMsg = fmt.Sprintf("update failed: %v, rollback failed: %v", err, rerr)
+		})
+		return
+	}
+
+	s.updateAppState(app, types.AppNormal, func(a *types.App) {
+		a.ProposedVersion = nil
+		a.Progress = nil
+		a.ErrMsg = fmt.Sprintf("update failed: %v, rolled back", err)
+	})
+}
+
+// finishUpdate unregister the update or replace it with the next one,
+// it returns true if the update has been aborted.
+func (s *Scheduler) finishUpdate(appID string, u, next *update) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if u.aborted {
+		return true
+	}
+	if next != nil {
+		s.updates[appID] = next
+	} else {
+		delete(s.updates, appID)
+	}
+	return false
+}
+
+// roll replace all of the app's tasks step by step with the tasks of the new version
+func (s *Scheduler) roll(app *types.App, u *update) error {
+	if u.to.IsDaemon() {
+		return s.rollDaemon(app, u)
+	}
+
+	var (
+		total      = u.total
+		step       = updateStep(u.to)
+		delay      time.Duration
+		maxRetries int
+	)
+	if p := u.to.UpdatePolicy; p != nil {
+		delay = time.Duration(p.UpdateDelay) * time.Second
+		maxRetries = int(p.MaxRetries)
+	}
+
+	killTimeout := updateKillTimeout
+	if p := u.from.KillPolicy; p != nil {
+		killTimeout += time.Duration(p.Duration) * time.Second
+	}
+
+	for {
+		launched, olds, err := s.splitTasks(app.ID, u)
+		if err != nil {
+			return err
+		}
+		if err := s.saveProgress(app, u, olds); err != nil {
+			return err
+		}
+
+		if launched >= total && len(olds) == 0 {
+			return nil
+		}
+
+		// surge first, so that the app keeps it's capacity
+		if n := minInt(step, total-launched); n > 0 {
+			s.Lock()
+			ids := s.pushNew(app.ID, u, n)
+			s.Unlock()
+
+			s.reviveIfNeeded()
+			s.schedule()
+
+			if err := s.waitReady(app, u, ids, maxRetries); err != nil {
+				return err
+			}
+		}
+
+		if n := minInt(step, len(olds)); n > 0 {
+			if err := s.KillAndWait(olds[:n], u.from.KillPolicy, false, killTimeout); err != nil {
+				return err
+			}
+		}
+
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// pushNew put `n` new tasks of the update into the launch queue
+// NOTE the caller should hold the lock
+func (s *Scheduler) pushNew(appID string, u *update, n int) []string {
+	ids := make([]string, 0, n)
+	for _, p := range s.push(appID, u.to, n, 0) {
+		p.weight = u.weight
+		u.tasks[p.TaskID] = true
+		ids = append(ids, p.TaskID)
+	}
+	return ids
+}
+
+// waitReady wait until the new tasks are running and healthy, the new tasks
+// died unexpectedly are relaunched until exceeded the UpdatePolicy's MaxRetries.
+func (s *Scheduler) waitReady(app *types.App, u *update, ids []string, maxRetries int) error {
+	timeout := updateStepTimeout
+	if hc := u.to.HealthCheck; hc != nil {
+		timeout += time.Duration(hc.GracePeriodSeconds+hc.DelaySeconds) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		s.Lock()
+		var (
+			aborted   = u.aborted || u.cancel
+			failures  = u.failures
+			lastError = u.lastError
+		)
+		for i, id := range ids {
+			if aborted || failures > maxRetries {
+				break
+			}
+			if !u.tasks[id] { // gone, relaunch it
+				if agentID, ok := u.pinned[id]; ok {
+					ids[i] = s.pushNewOn(app.ID, u, agentID)
+				} else {
+					ids[i] = s.pushNew(app.ID, u, 1)[0]
+				}
+			}
+		}
+		s.Unlock()
+
+		if aborted {
+			return errUpdateAborted
+		}
+		if failures > maxRetries {
+			return fmt.Errorf("%d new tasks failed, the last: %s", failures, lastError)
+		}
+
+		var ready int
+		for _, id := range ids {
+			if t, err := store.DB().GetTask(app.ID, id); err == nil && isReady(t, u.to) {
+				ready++
+			}
+		}
+		if ready == len(ids) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d of %d new tasks not ready after %s", len(ids)-ready, len(ids), timeout)
+		}
+		time.Sleep(updatePollInterval)
+	}
+}
+
+// splitTasks return the nb of the launched new tasks, and the alive old tasks to be replaced
+func (s *Scheduler) splitTasks(appID string, u *update) (int, []*types.Task, error) {
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if u.aborted {
+		return 0, nil, errUpdateAborted
+	}
+
+	olds := make([]*types.Task, 0)
+	for _, t := range tasks {
+		if u.tasks[t.ID] || !isAlive(t.State) {
+			continue
+		}
+		if u.selected != nil && !u.selected[t.ID] {
+			continue
+		}
+		if _, ok := s.killing[t.ID]; ok {
+			continue
+		}
+		if u.adopt && t.VersionID == u.to.ID {
+			u.tasks[t.ID] = true
+			continue
+		}
+		olds = append(olds, t)
+	}
+	return len(u.tasks), olds, nil
+}
+
+// saveProgress persist the update progress, and emit an event if it changed
+func (s *Scheduler) saveProgress(app *types.App, u *update, olds []*types.Task) error {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	var ready int
+	for _, t := range tasks {
+		if u.tasks[t.ID] && isReady(t, u.to) {
+			ready++
+		}
+	}
+	p := *app.Progress
+	p.Launched = len(u.tasks)
+	p.Ready = ready
+	p.Remaining = len(olds)
+	p.Failures = u.failures
+	p.Message = u.lastError
+	aborted := u.aborted
+	s.Unlock()
+
+	if aborted {
+		return errUpdateAborted
+	}
+	if p == *app.Progress {
+		return nil
+	}
+
+	s.updateAppState(app, app.State, func(a *types.App) { a.Progress = &p })
+	s.emit(&types.Event{
+		ID:      app.ID,
+		Status:  app.State,
+		From:    app.State,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("%d/%d ready, %d old remaining", p.Ready, p.Total, p.Remaining),
+	})
+	return nil
+}
+
+// updateTaskGone record the gone task if it's launched by the app's ongoing update,
+// it returns true if the app is being updated, the update takes care of relaunching.
+func (s *Scheduler) updateTaskGone(task *types.Task, killed bool) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	u, ok := s.updates[task.AppID]
+	if !ok {
+		return false
+	}
+
+	if !u.tasks[task.ID] {
+		return !u.parallel
+	}
+
+	delete(u.tasks, task.ID)
+	if !killed {
+		u.failures++
+		u.lastError = fmt.Sprintf("task %s %s: %s", task.ID, task.State, task.Message)
+	}
+	return true
+}
+
+// discard kill the new tasks of the update and bring the app back to the current version
+func (s *Scheduler) discard(app *types.App, u *update, cause error) {
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		log.Errorf("list tasks of app %s error: %v", app.ID, err)
+	}
+
+	s.Lock()
+	u.soaking = false
+	for id := range u.tasks {
+		if s.queue.remove(id) != nil {
+			delete(u.tasks, id)
+		}
+	}
+	news := make([]*types.Task, 0)
+	for _, t := range tasks {
+		if u.tasks[t.ID] {
+			news = append(news, t)
+		}
+	}
+	s.Unlock()
+
+	timeout := updateKillTimeout
+	if p := u.to.KillPolicy; p != nil {
+		timeout += time.Duration(p.Duration) * time.Second
+	}
+	if err := s.KillAndWait(news, u.to.KillPolicy, false, timeout); err != nil {
+		log.Errorf("kill new tasks of app %s error: %v", app.ID, err)
+	}
+
+	if s.finishUpdate(app.ID, u, nil) {
+		return
+	}
+
+	if err := s.resetWeights(app.ID); err != nil {
+		log.Errorf("reset weights of app %s error: %v", app.ID, err)
+	}
+
+	log.Printf("%s update of app %s discarded: %v", u.strategy, app.ID, cause)
+	s.updateAppState(app, types.AppNormal, func(a *types.App) {
+		a.ProposedVersion = nil
+		a.Progress = nil
+		if cause != nil {
+			a.ErrMsg = fmt.Sprintf("%s update aborted: %v", u.strategy, cause)
+		}
+	})
+
+	// make sure the current tasks died while updating are relaunched
+	s.fillUp(app)
+}
+
+// resetWeights route the app's traffic evenly to all of it's tasks
+func (s *Scheduler) resetWeights(appID string) error {
+	tasks, err := store.DB().ListTasks(appID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if err := setWeight(appID, t, defaultTaskWeight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setWeight(appID string, t *types.Task, weight float64) error {
+	if t.Weight == weight {
+		return nil
+	}
+	t.Weight = weight
+	return store.DB().UpdateTask(appID, t)
+}
+
+// fillUp launch the missing instances of the app
+func (s *Scheduler) fillUp(app *types.App) {
+	if app.Version.IsDaemon() {
+		s.syncDaemon(app)
+		return
+	}
+
+	tasks, err := store.DB().ListTasks(app.ID)
+	if err != nil {
+		log.Errorf("list tasks of app %s error: %v", app.ID, err)
+		return
+	}
+
+	s.Lock()
+	n := int(app.Version.Instances) - s.queue.countApp(app.ID)
+	for _, t := range tasks {
+		if _, ok := s.killing[t.ID]; !ok && isAlive(t.State) {
+			n--
+		}
+	}
+	s.Unlock()
+
+	if n > 0 {
+		log.Printf("launching %d missing instances of app %s", n, app.ID)
+		s.Enqueue(app, n)
+	}
+}
+
+// isReady check if the task is running, and healthy if the health check is set
+func isReady(t *types.Task, ver *types.AppVersion) bool {
+	if t.State != "TASK_RUNNING" {
+		return false
+	}
+	if ver.HealthCheck != nil {
+		return t.Healthy == "healthy"
+	}
+	return t.Healthy != "unhealthy"
+}
+
+func updateStrategy(ver *types.AppVersion) string {
+	if p := ver.UpdatePolicy; p != nil && p.Strategy != "" {
+		return p.Strategy
+	}
+	return "rolling"
+}
+
+func updateStep(ver *types.AppVersion) int {
+	if p := ver.UpdatePolicy; p != nil && p.Step > 0 {
+		return int(p.Step)
+	}
+	return 1
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+// StampVersion give the app version a new id and the created time,
+// the id is the created time in unix nano, so the ids are ordered by time.
+func StampVersion(appID string, ver *types.AppVersion) {
+	now := time.Now().UnixNano()
+	ver.ID = fmt.Sprintf("%d", now)
+	ver.AppID = appID
+	ver.CreatedAt = now
+}
+
+// SaveVersion persist the app version to be applied, and garbage collect
+// the oldest versions beyond the retention count. the saved one, and the app's
+// current and proposed versions are never collected.
+func (s *Scheduler) SaveVersion(appID string, ver *types.AppVersion) error {
+	if err := store.DB().CreateVersion(appID, ver); err != nil {
+		return err
+	}
+
+	app, err := store.DB().GetApp(appID)
+	if err != nil {
+		return err
+	}
+
+	keep := map[string]bool{ver.ID: true}
+	if app.Version != nil {
+		keep[app.Version.ID] = true
+	}
+	if app.ProposedVersion != nil {
+		keep[app.ProposedVersion.ID] = true
+	}
+
+	vers, err := store.DB().ListVersions(appID)
+	if err != nil {
+		return err
+	}
+
+	for i := s.cfg.VersionRetention; i < len(vers); i++ {
+		if keep[vers[i].ID] {
+			continue
+		}
+		log.Printf("garbage collecting version %s of app %s", vers[i].ID, appID)
+		if err := store.DB().DeleteVersion(appID, vers[i].ID); err != nil {
+			log.Errorf("remove version %s of app %s error: %v", vers[i].ID, appID, err)
+		}
+	}
+	return nil
+}
+
+// RollbackApp rolling update the app back to the copy of the previous version,
+// the instances are kept as the current, as the scalings are not versioned.
+func (s *Scheduler) RollbackApp(app *types.App, ver *types.AppVersion) error {
+	cp := *ver
+	cp.Instances = app.Version.Instances
+	StampVersion(app.ID, &cp)
+	return s.startUpdate(app, &cp, "rolling")
+}
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bbklab/swan-ng/store"
+	"github.com/bbklab/swan-ng/types"
+)
+
+func TestSaveVersionKeepsLive(t *testing.T) {
+	if err := store.Setup("memory", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		s   = New(&types.MgrConfig{VersionRetention: 2}, nil, nil)
+		cur = &types.AppVersion{ID: "100", AppName: "demo"}
+		app = &types.App{ID: "demo", State: types.AppNormal, Version: cur}
+	)
+	if err := store.DB().CreateApp(app); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveVersion(app.ID, cur); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed update attempts exceed the retention
+	for i := 1; i <= 3; i++ {
+		if err := s.SaveVersion(app.ID, &types.AppVersion{ID: fmt.Sprintf("10%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vers, err := store.DB().ListVersions(app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vers) != 3 {
+		t.Fatalf("expect 3 versions retained, got %d", len(vers))
+	}
+	if _, err := store.DB().GetVersion(app.ID, cur.ID); err != nil {
+		t.Fatalf("the current version should be kept: %v", err)
+	}
+}
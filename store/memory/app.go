@@ -1,60 +1,233 @@
 package memory
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/bbklab/swan-ng/types"
 )
 
+//
+// app CRUD
+//
+
 // CreateApp ...
 func (s *Store) CreateApp(app *types.App) error {
-	return nil
+	bs, err := encode(app)
+	if err != nil {
+		return err
+	}
+
+	return s.setNew(keyApp+"/"+app.ID, bs)
 }
 
 // UpdateApp ...
 func (s *Store) UpdateApp(app *types.App) error {
+	bs, err := encode(app)
+	if err != nil {
+		return err
+	}
+
+	s.set(keyApp+"/"+app.ID, bs)
 	return nil
 }
 
 // GetApp ...
 func (s *Store) GetApp(id string) (*types.App, error) {
-	return nil, nil
+	bs, err := s.get(keyApp + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	app := new(types.App)
+	if err := decode(bs, &app); err != nil {
+		return nil, err
+	}
+
+	return app, nil
 }
 
 // ListApps ...
 func (s *Store) ListApps() ([]*types.App, error) {
-	return nil, nil
+	nodes := s.list(keyApp)
+
+	ret := make([]*types.App, 0, len(nodes))
+	for _, node := range nodes {
+		app, err := s.GetApp(node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, app)
+	}
+
+	return ret, nil
 }
 
-// DeleteApp ...
+// DeleteApp remove the app with all of it's versions & tasks
 func (s *Store) DeleteApp(id string) error {
+	s.del(keyApp + "/" + id)
 	return nil
 }
 
+//
+// app's version
+//
+
 // CreateVersion ...
-func (s *Store) CreateVersion(aid string, ver *types.Version) error {
-	return nil
+func (s *Store) CreateVersion(aid string, ver *types.AppVersion) error {
+	bs, err := encode(ver)
+	if err != nil {
+		return err
+	}
+
+	return s.setIn(keyApp+"/"+aid, keyApp+"/"+aid+"/versions/"+ver.ID, bs)
 }
 
 // GetVersion ...
-func (s *Store) GetVersion(aid, vid string) (*types.Version, error) {
-	return nil, nil
+func (s *Store) GetVersion(aid, vid string) (*types.AppVersion, error) {
+	bs, err := s.get(keyApp + "/" + aid + "/versions/" + vid)
+	if err != nil {
+		return nil, err
+	}
+
+	ver := new(types.AppVersion)
+	if err := decode(bs, &ver); err != nil {
+		return nil, err
+	}
+
+	return ver, nil
 }
 
-// ListVersions ...
-func (s *Store) ListVersions(aid string) ([]*types.Version, error) {
-	return nil, nil
+// ListVersions return the app's versions, the newest first
+func (s *Store) ListVersions(aid string) ([]*types.AppVersion, error) {
+	nodes := s.list(keyApp + "/" + aid + "/versions")
+	sort.Sort(sort.Reverse(sort.StringSlice(nodes)))
+
+	ret := make([]*types.AppVersion, 0, len(nodes))
+	for _, node := range nodes {
+		ver, err := s.GetVersion(aid, node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ver)
+	}
+
+	return ret, nil
 }
 
-// ListTasks ...
-func (s *Store) ListTasks(aid string) ([]*types.Task, error) {
-	return nil, nil
+// DeleteVersion ...
+func (s *Store) DeleteVersion(aid, vid string) error {
+	s.del(keyApp + "/" + aid + "/versions/" + vid)
+	return nil
 }
 
+//
+// app's tasks
+//
+
 // UpdateTask ...
 func (s *Store) UpdateTask(aid string, t *types.Task) error {
+	bs, err := encode(t)
+	if err != nil {
+		return err
+	}
+
+	return s.setIn(keyApp+"/"+aid, keyApp+"/"+aid+"/tasks/"+t.ID, bs)
+}
+
+// UpdateTasks ...
+func (s *Store) UpdateTasks(aid string, ts []*types.Task) error {
+	kvs := make(map[string][]byte, len(ts))
+	for _, t := range ts {
+		bs, err := encode(t)
+		if err != nil {
+			return err
+		}
+		kvs[keyApp+"/"+aid+"/tasks/"+t.ID] = bs
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.kv[keyApp+"/"+aid]; !ok {
+		return ErrNotFound
+	}
+	for path, bs := range kvs {
+		s.kv[path] = bs
+	}
+	return nil
+}
+
+// GetTask ...
+func (s *Store) GetTask(aid, tid string) (*types.Task, error) {
+	bs, err := s.get(keyApp + "/" + aid + "/tasks/" + tid)
+	if err != nil {
+		return nil, err
+	}
+
+	task := new(types.Task)
+	if err := decode(bs, &task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// ListTasks ...
+func (s *Store) ListTasks(aid string) ([]*types.Task, error) {
+	nodes := s.list(keyApp + "/" + aid + "/tasks")
+
+	ret := make([]*types.Task, 0, len(nodes))
+	for _, node := range nodes {
+		task, err := s.GetTask(aid, node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, task)
+	}
+
+	return ret, nil
+}
+
+// DeleteTask ...
+func (s *Store) DeleteTask(aid, tid string) error {
+	s.del(keyApp + "/" + aid + "/tasks/" + tid)
 	return nil
 }
 
 // GetTaskHistories ...
 func (s *Store) GetTaskHistories(aid, tid string) ([]*types.Task, error) {
-	return nil, nil
+	path := keyApp + "/" + aid + "/histories/" + tid
+	nodes := s.list(path)
+
+	ret := make([]*types.Task, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(path + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		h := new(types.Task)
+		if err := decode(bs, &h); err != nil {
+			return nil, err
+		}
+		ret = append(ret, h)
+	}
+
+	return ret, nil
+}
+
+// AddTaskHistory ...
+func (s *Store) AddTaskHistory(aid, tid string, h *types.Task) error {
+	bs, err := encode(h)
+	if err != nil {
+		return err
+	}
+
+	return s.setIn(keyApp+"/"+aid, fmt.Sprintf("%s/%s/histories/%s/%d", keyApp, aid, tid, h.ArchivedAt), bs)
+}
+
+// DeleteTaskHistories ...
+func (s *Store) DeleteTaskHistories(aid, tid string) error {
+	s.del(keyApp + "/" + aid + "/histories/" + tid)
+	return nil
 }
@@ -10,25 +10,64 @@ import (
 
 // CreateInstance ...
 func (s *Store) CreateInstance(ins *types.Instance) error {
-	return nil
+	bs, err := encode(ins)
+	if err != nil {
+		return err
+	}
+
+	return s.setNew(keyInstance+"/"+ins.ID, bs)
 }
 
 // UpdateInstance ...
 func (s *Store) UpdateInstance(ins *types.Instance) error {
+	bs, err := encode(ins)
+	if err != nil {
+		return err
+	}
+
+	s.set(keyInstance+"/"+ins.ID, bs)
 	return nil
 }
 
 // GetInstance ...
 func (s *Store) GetInstance(id string) (*types.Instance, error) {
-	return nil, nil
+	bs, err := s.get(keyInstance + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := new(types.Instance)
+	if err := decode(bs, &ins); err != nil {
+		return nil, err
+	}
+
+	return ins, nil
 }
 
 // ListInstances ...
 func (s *Store) ListInstances() ([]*types.Instance, error) {
-	return nil, nil
+	nodes := s.list(keyInstance)
+
+	ret := make([]*types.Instance, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(keyInstance + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		ins := new(types.Instance)
+		if err := decode(bs, &ins); err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, ins)
+	}
+
+	return ret, nil
 }
 
 // DeleteInstance ...
 func (s *Store) DeleteInstance(id string) error {
+	s.del(keyInstance + "/" + id)
 	return nil
 }
@@ -1,11 +1,18 @@
 package memory
 
+const keyFramework = "/framework"
+
 // GetFrameworkID ...
 func (s *Store) GetFrameworkID() string {
-	return ""
+	bs, err := s.get(keyFramework)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
 }
 
 // UpdateFrameworkID ...
 func (s *Store) UpdateFrameworkID(id string) error {
+	s.set(keyFramework, []byte(id))
 	return nil
 }
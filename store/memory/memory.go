@@ -1,10 +1,120 @@
 package memory
 
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	keyApp      = "/app"      // single app
+	keyInstance = "/instance" // compose instance (group apps)
+	keyAgent    = "/agent"    // agent maintenance state
+	keyCronJob  = "/cronjob"  // cron job
+)
+
+var (
+	// ErrNotFound represents the requested key not exists
+	ErrNotFound = errors.New("memory: key not found")
+	// ErrExists represents the key to be created already exists
+	ErrExists = errors.New("memory: key already exists")
+)
+
 // Store represents memory store
+// NOTE it keeps the same key layout as the zk store, values are json encoded
+// to avoid sharing the stored objects with the callers.
 type Store struct {
+	sync.RWMutex                   // protect kv
+	kv           map[string][]byte // path -> data
 }
 
 // New ...
 func New() *Store {
-	return new(Store)
+	return &Store{
+		kv: make(map[string][]byte),
+	}
+}
+
+func (s *Store) get(path string) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	data, ok := s.kv[path]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (s *Store) set(path string, data []byte) {
+	s.Lock()
+	s.kv[path] = data
+	s.Unlock()
+}
+
+// setNew set the path only if not exists, it returns ErrExists otherwise
+func (s *Store) setNew(path string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.kv[path]; ok {
+		return ErrExists
+	}
+	s.kv[path] = data
+	return nil
+}
+
+// setIn set the path under the base path, it returns ErrNotFound if the base not exists,
+// so the children of the removed objects are never recreated.
+func (s *Store) setIn(base, path string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.kv[base]; !ok {
+		return ErrNotFound
+	}
+	s.kv[path] = data
+	return nil
+}
+
+// del remove the path and all of it's children
+func (s *Store) del(path string) {
+	s.Lock()
+	defer s.Unlock()
+	for key := range s.kv {
+		if key == path || strings.HasPrefix(key, path+"/") {
+			delete(s.kv, key)
+		}
+	}
+}
+
+// list return the sorted direct children names of the path
+func (s *Store) list(path string) []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	var (
+		prefix = path + "/"
+		seen   = make(map[string]bool)
+		ret    = make([]string, 0)
+	)
+	for key := range s.kv {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		child := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)[0]
+		if !seen[child] {
+			seen[child] = true
+			ret = append(ret, child)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// encode & decode is just short-hands for json Marshal/Unmarshal
+func encode(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+func decode(bs []byte, v interface{}) error {
+	return json.Unmarshal(bs, v)
 }
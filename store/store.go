@@ -32,6 +32,16 @@ func DB() Store {
 	return db
 }
 
+// IsNotFound check if the error returned by the store means the requested object not exists
+func IsNotFound(err error) bool {
+	return err == memory.ErrNotFound || err == zk.ErrNotFound
+}
+
+// IsExists check if the error returned by the store means the object to be created already exists
+func IsExists(err error) bool {
+	return err == memory.ErrExists || err == zk.ErrExists
+}
+
 // Store interface defination
 type Store interface {
 	// framework
@@ -39,24 +49,42 @@ type Store interface {
 	UpdateFrameworkID(id string) error
 
 	// app CRUD
-	CreateApp(app *types.App) error
+	CreateApp(app *types.App) error // fails if exists, see IsExists
 	UpdateApp(app *types.App) error
 	GetApp(id string) (*types.App, error)
 	ListApps() ([]*types.App, error)
 	DeleteApp(id string) error
-	// app's setting version
-	CreateVersion(aid string, ver *types.Version) error
-	GetVersion(aid, vid string) (*types.Version, error)
-	ListVersions(aid string) ([]*types.Version, error)
-	// app's tasks
+	// app's setting version, the writes fail with not found if the app not exists
+	CreateVersion(aid string, ver *types.AppVersion) error
+	GetVersion(aid, vid string) (*types.AppVersion, error)
+	ListVersions(aid string) ([]*types.AppVersion, error) // newest first
+	DeleteVersion(aid, vid string) error
+	// app's tasks, the writes fail with not found if the app not exists
 	UpdateTask(aid string, t *types.Task) error              // update app's specified task
+	UpdateTasks(aid string, ts []*types.Task) error          // update app's tasks atomically
+	GetTask(aid, tid string) (*types.Task, error)            // app's specified task
 	ListTasks(aid string) ([]*types.Task, error)             // app's task list
-	GetTaskHistories(aid, tid string) ([]*types.Task, error) // app's specified task's histories
+	DeleteTask(aid, tid string) error                        // remove app's specified task
+	GetTaskHistories(aid, tid string) ([]*types.Task, error) // app's specified task's histories, oldest first
+	AddTaskHistory(aid, tid string, h *types.Task) error     // archive a previous run of the app's specified task
+	DeleteTaskHistories(aid, tid string) error               // remove app's specified task's histories
+
+	// agent's maintenance state
+	UpdateAgent(agent *types.AgentMaintenance) error
+	ListAgents() ([]*types.AgentMaintenance, error)
+	DeleteAgent(id string) error
 
 	// compose instance CRUD
-	CreateInstance(ins *types.Instance) error
+	CreateInstance(ins *types.Instance) error // fails if exists, see IsExists
 	UpdateInstance(ins *types.Instance) error // status, errmsg, updateAt
 	GetInstance(id string) (*types.Instance, error)
 	ListInstances() ([]*types.Instance, error)
 	DeleteInstance(id string) error
+
+	// cron job CRUD
+	CreateCronJob(cj *types.CronJob) error // fails if exists, see IsExists
+	UpdateCronJob(cj *types.CronJob) error // runs, schedule times
+	GetCronJob(id string) (*types.CronJob, error)
+	ListCronJobs() ([]*types.CronJob, error)
+	DeleteCronJob(id string) error
 }
@@ -0,0 +1,49 @@
+package zk
+
+import (
+	"github.com/bbklab/swan-ng/types"
+)
+
+//
+// agent's maintenance state
+//
+
+// UpdateAgent ...
+func (s *Store) UpdateAgent(agent *types.AgentMaintenance) error {
+	bs, err := encode(agent)
+	if err != nil {
+		return err
+	}
+
+	return s.createAll(keyAgent+"/"+agent.ID, bs)
+}
+
+// ListAgents ...
+func (s *Store) ListAgents() ([]*types.AgentMaintenance, error) {
+	nodes, err := s.list(keyAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*types.AgentMaintenance, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(keyAgent + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		agent := new(types.AgentMaintenance)
+		if err := decode(bs, &agent); err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, agent)
+	}
+
+	return ret, nil
+}
+
+// DeleteAgent ...
+func (s *Store) DeleteAgent(id string) error {
+	return s.del(keyAgent + "/" + id)
+}
@@ -1,6 +1,11 @@
 package zk
 
 import (
+	"fmt"
+	"sort"
+
+	"github.com/samuel/go-zookeeper/zk"
+
 	"github.com/bbklab/swan-ng/types"
 )
 
@@ -16,7 +21,7 @@ func (s *Store) CreateApp(app *types.App) error {
 	}
 
 	path := keyApp + "/" + app.ID
-	return s.createAll(path, bs)
+	return s.createNew(path, bs)
 }
 
 // UpdateApp ...
@@ -70,45 +75,192 @@ func (s *Store) ListApps() ([]*types.App, error) {
 	return ret, nil
 }
 
-// DeleteApp ...
+// DeleteApp remove the app with all of it's versions & tasks
 func (s *Store) DeleteApp(id string) error {
-	return s.del(keyApp + "/" + id)
+	return s.delAll(keyApp + "/" + id)
 }
 
 //
-// TODO app's version
+// app's version
 //
 
 // CreateVersion ...
-func (s *Store) CreateVersion(aid string, ver *types.Version) error {
-	return nil
+func (s *Store) CreateVersion(aid string, ver *types.AppVersion) error {
+	bs, err := encode(ver)
+	if err != nil {
+		return err
+	}
+
+	path := keyApp + "/" + aid + "/versions/" + ver.ID
+	return s.createIn(keyApp+"/"+aid, path, bs)
 }
 
 // GetVersion ...
-func (s *Store) GetVersion(aid, vid string) (*types.Version, error) {
-	return nil, nil
+func (s *Store) GetVersion(aid, vid string) (*types.AppVersion, error) {
+	bs, err := s.get(keyApp + "/" + aid + "/versions/" + vid)
+	if err != nil {
+		return nil, err
+	}
+
+	ver := new(types.AppVersion)
+	if err := decode(bs, &ver); err != nil {
+		return nil, err
+	}
+
+	return ver, nil
+}
+
+// ListVersions return the app's versions, the newest first
+func (s *Store) ListVersions(aid string) ([]*types.AppVersion, error) {
+	nodes, err := s.list(keyApp + "/" + aid + "/versions")
+	if err != nil {
+		if err == ErrNotFound {
+			return []*types.AppVersion{}, nil
+		}
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(nodes)))
+
+	ret := make([]*types.AppVersion, 0, len(nodes))
+	for _, node := range nodes {
+		ver, err := s.GetVersion(aid, node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ver)
+	}
+
+	return ret, nil
 }
 
-// ListVersions ...
-func (s *Store) ListVersions(aid string) ([]*types.Version, error) {
-	return nil, nil
+// DeleteVersion ...
+func (s *Store) DeleteVersion(aid, vid string) error {
+	return s.del(keyApp + "/" + aid + "/versions/" + vid)
 }
 
 //
-// TODO app's tasks
+// app's tasks
 //
 
+// UpdateTask ...
+func (s *Store) UpdateTask(aid string, t *types.Task) error {
+	bs, err := encode(t)
+	if err != nil {
+		return err
+	}
+
+	path := keyApp + "/" + aid + "/tasks/" + t.ID
+	return s.createIn(keyApp+"/"+aid, path, bs)
+}
+
+// UpdateTasks update the existing tasks within a single zk transaction
+func (s *Store) UpdateTasks(aid string, ts []*types.Task) error {
+	ops := make([]interface{}, 0, len(ts))
+	for _, t := range ts {
+		bs, err := encode(t)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, &zk.SetDataRequest{
+			Path:    s.clean(keyApp + "/" + aid + "/tasks/" + t.ID),
+			Data:    bs,
+			Version: -1,
+		})
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	_, err := s.conn.Multi(ops...)
+	return err
+}
+
+// GetTask ...
+func (s *Store) GetTask(aid, tid string) (*types.Task, error) {
+	bs, err := s.get(keyApp + "/" + aid + "/tasks/" + tid)
+	if err != nil {
+		return nil, err
+	}
+
+	task := new(types.Task)
+	if err := decode(bs, &task); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
 // ListTasks ...
 func (s *Store) ListTasks(aid string) ([]*types.Task, error) {
-	return nil, nil
+	nodes, err := s.list(keyApp + "/" + aid + "/tasks")
+	if err != nil {
+		if err == ErrNotFound {
+			return []*types.Task{}, nil
+		}
+		return nil, err
+	}
+
+	ret := make([]*types.Task, 0, len(nodes))
+	for _, node := range nodes {
+		task, err := s.GetTask(aid, node)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, task)
+	}
+
+	return ret, nil
 }
 
-// UpdateTask ...
-func (s *Store) UpdateTask(aid string, t *types.Task) error {
-	return nil
+// DeleteTask ...
+func (s *Store) DeleteTask(aid, tid string) error {
+	return s.del(keyApp + "/" + aid + "/tasks/" + tid)
 }
 
 // GetTaskHistories ...
 func (s *Store) GetTaskHistories(aid, tid string) ([]*types.Task, error) {
-	return nil, nil
+	path := keyApp + "/" + aid + "/histories/" + tid
+	nodes, err := s.list(path)
+	if err != nil {
+		if err == ErrNotFound {
+			return []*types.Task{}, nil
+		}
+		return nil, err
+	}
+
+	sort.Strings(nodes)
+
+	ret := make([]*types.Task, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(path + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		h := new(types.Task)
+		if err := decode(bs, &h); err != nil {
+			return nil, err
+		}
+		ret = append(ret, h)
+	}
+
+	return ret, nil
+}
+
+// AddTaskHistory ...
+func (s *Store) AddTaskHistory(aid, tid string, h *types.Task) error {
+	bs, err := encode(h)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/%s/histories/%s/%d", keyApp, aid, tid, h.ArchivedAt)
+	return s.createIn(keyApp+"/"+aid, path, bs)
+}
+
+// DeleteTaskHistories ...
+func (s *Store) DeleteTaskHistories(aid, tid string) error {
+	return s.delAll(keyApp + "/" + aid + "/histories/" + tid)
 }
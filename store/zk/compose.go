@@ -10,25 +10,65 @@ import (
 
 // CreateInstance ...
 func (s *Store) CreateInstance(ins *types.Instance) error {
-	return nil
+	bs, err := encode(ins)
+	if err != nil {
+		return err
+	}
+
+	return s.createNew(keyInstance+"/"+ins.ID, bs)
 }
 
 // UpdateInstance ...
 func (s *Store) UpdateInstance(ins *types.Instance) error {
-	return nil
+	bs, err := encode(ins)
+	if err != nil {
+		return err
+	}
+
+	return s.create(keyInstance+"/"+ins.ID, bs)
 }
 
 // GetInstance ...
 func (s *Store) GetInstance(id string) (*types.Instance, error) {
-	return nil, nil
+	bs, err := s.get(keyInstance + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := new(types.Instance)
+	if err := decode(bs, &ins); err != nil {
+		return nil, err
+	}
+
+	return ins, nil
 }
 
 // ListInstances ...
 func (s *Store) ListInstances() ([]*types.Instance, error) {
-	return nil, nil
+	nodes, err := s.list(keyInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*types.Instance, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(keyInstance + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		ins := new(types.Instance)
+		if err := decode(bs, &ins); err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, ins)
+	}
+
+	return ret, nil
 }
 
 // DeleteInstance ...
 func (s *Store) DeleteInstance(id string) error {
-	return nil
+	return s.delAll(keyInstance + "/" + id)
 }
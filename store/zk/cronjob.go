@@ -0,0 +1,74 @@
+package zk
+
+import (
+	"github.com/bbklab/swan-ng/types"
+)
+
+//
+// cron job CRUD
+//
+
+// CreateCronJob ...
+func (s *Store) CreateCronJob(cj *types.CronJob) error {
+	bs, err := encode(cj)
+	if err != nil {
+		return err
+	}
+
+	return s.createNew(keyCronJob+"/"+cj.ID, bs)
+}
+
+// UpdateCronJob ...
+func (s *Store) UpdateCronJob(cj *types.CronJob) error {
+	bs, err := encode(cj)
+	if err != nil {
+		return err
+	}
+
+	return s.create(keyCronJob+"/"+cj.ID, bs)
+}
+
+// GetCronJob ...
+func (s *Store) GetCronJob(id string) (*types.CronJob, error) {
+	bs, err := s.get(keyCronJob + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	cj := new(types.CronJob)
+	if err := decode(bs, &cj); err != nil {
+		return nil, err
+	}
+
+	return cj, nil
+}
+
+// ListCronJobs ...
+func (s *Store) ListCronJobs() ([]*types.CronJob, error) {
+	nodes, err := s.list(keyCronJob)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*types.CronJob, 0, len(nodes))
+	for _, node := range nodes {
+		bs, err := s.get(keyCronJob + "/" + node)
+		if err != nil {
+			return nil, err
+		}
+
+		cj := new(types.CronJob)
+		if err := decode(bs, &cj); err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, cj)
+	}
+
+	return ret, nil
+}
+
+// DeleteCronJob ...
+func (s *Store) DeleteCronJob(id string) error {
+	return s.delAll(keyCronJob + "/" + id)
+}
@@ -2,6 +2,7 @@ package zk
 
 import (
 	"encoding/json"
+	"errors"
 	"net/url"
 	"path"
 	"strings"
@@ -14,6 +15,15 @@ import (
 const (
 	keyApp      = "/app"      // single app
 	keyInstance = "/instance" // compose instance (group apps)
+	keyAgent    = "/agent"    // agent maintenance state
+	keyCronJob  = "/cronjob"  // cron job
+)
+
+var (
+	// ErrNotFound represents the requested zk node not exists
+	ErrNotFound = errors.New("zk: node not found")
+	// ErrExists represents the zk node to be created already exists
+	ErrExists = errors.New("zk: node already exists")
 )
 
 // Store represents zk store
@@ -39,7 +49,7 @@ func New(url *url.URL) (*Store, error) {
 	}
 
 	// create base keys nodes
-	for _, node := range []string{keyApp, keyInstance} {
+	for _, node := range []string{keyApp, keyInstance, keyAgent, keyCronJob} {
 		if err := s.createAll(node, nil); err != nil {
 			return nil, err
 		}
@@ -58,6 +68,9 @@ func (s *Store) clean(p string) string {
 
 func (s *Store) get(path string) (data []byte, err error) {
 	data, _, err = s.conn.Get(s.clean(path))
+	if err == zk.ErrNoNode {
+		err = ErrNotFound
+	}
 	return
 }
 
@@ -72,8 +85,30 @@ func (s *Store) del(path string) error {
 	return s.conn.Delete(s.clean(path), -1)
 }
 
+// delAll remove the path and all of it's children recursively
+func (s *Store) delAll(path string) error {
+	children, err := s.list(path)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range children {
+		if err := s.delAll(path + "/" + child); err != nil {
+			return err
+		}
+	}
+
+	return s.del(path)
+}
+
 func (s *Store) list(path string) (children []string, err error) {
 	children, _, err = s.conn.Children(s.clean(path))
+	if err == zk.ErrNoNode {
+		err = ErrNotFound
+	}
 	return
 }
 
@@ -82,30 +117,84 @@ func (s *Store) exist(path string) (exist bool, err error) {
 	return
 }
 
+// createAll create the path with the data, and all of the missing parent nodes.
+// the data of the existing parent nodes is untouched.
 func (s *Store) createAll(path string, data []byte) error {
 	path = s.clean(path)
 
+	if err := s.createParents(path); err != nil {
+		return err
+	}
+
+	// the end data node
+	return s.create(path, data)
+}
+
+// createNew create the path with the data and all of the missing parent nodes,
+// it returns ErrExists if the path already exists.
+func (s *Store) createNew(path string, data []byte) error {
+	path = s.clean(path)
+
+	if err := s.createParents(path); err != nil {
+		return err
+	}
+
+	_, err := s.conn.Create(path, data, 0, s.acl)
+	if err == zk.ErrNodeExists {
+		err = ErrExists
+	}
+	return err
+}
+
+// createIn create or set the path with the data under the existing base node, and
+// the missing nodes between them. it returns ErrNotFound if the base not exists, so
+// the children of the removed objects never recreate them.
+func (s *Store) createIn(base, path string, data []byte) error {
+	base, path = s.clean(base), s.clean(path)
+
+	var (
+		fields = strings.Split(strings.TrimPrefix(path, base+"/"), "/")
+		node   = base
+	)
+
+	for _, v := range fields[:len(fields)-1] {
+		node += "/" + v
+		_, err := s.conn.Create(node, nil, 0, s.acl)
+		if err == zk.ErrNoNode {
+			return ErrNotFound
+		}
+		if err != nil && err != zk.ErrNodeExists {
+			log.Errorf("create node: %s error: %v", node, err)
+			return err
+		}
+	}
+
+	err := s.create(path, data)
+	if err == zk.ErrNoNode {
+		err = ErrNotFound
+	}
+	return err
+}
+
+// createParents create the missing parent nodes of the path without data
+func (s *Store) createParents(path string) error {
+	path = s.clean(path)
+
 	var (
 		fields = strings.Split(path, "/")
-		node   = "/"
+		node   = ""
 	)
 
-	// all of dir node
-	for i, v := range fields[1:] {
-		node += v
-		if i >= len(fields[1:])-1 {
-			break // the end node
-		}
-		err := s.create(node, nil)
-		if err != nil {
+	for _, v := range fields[1 : len(fields)-1] {
+		node += "/" + v
+		_, err := s.conn.Create(node, nil, 0, s.acl)
+		if err != nil && err != zk.ErrNodeExists {
 			log.Errorf("create node: %s error: %v", node, err)
 			return err
 		}
-		node += "/"
 	}
 
-	// the end data node
-	return s.create(node, data)
+	return nil
 }
 
 func (s *Store) create(path string, data []byte) error {
@@ -3,6 +3,8 @@ package zk
 import (
 	"net/url"
 	"testing"
+
+	"github.com/bbklab/swan-ng/types"
 )
 
 func TestZK(t *testing.T) {
@@ -68,3 +70,46 @@ func TestZK(t *testing.T) {
 		t.Logf("delete %s succeed", node)
 	}
 }
+
+// writing the app's children should never overwrite or recreate the app node
+func TestUpdateTaskKeepsApp(t *testing.T) {
+	url, _ := url.Parse("zk://bbklab.net:2181/swan")
+	s, err := New(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &types.App{ID: "zk-test-app", Name: "zk-test-app", State: types.AppNormal}
+	if err := s.CreateApp(app); err != nil {
+		t.Fatal(err)
+	}
+	defer s.DeleteApp(app.ID)
+
+	if err := s.UpdateTask(app.ID, &types.Task{ID: "zk-test-task", AppID: app.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateVersion(app.ID, &types.AppVersion{ID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTaskHistory(app.ID, "zk-test-task", &types.Task{ID: "zk-test-prev", ArchivedAt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.GetApp(app.ID)
+	if err != nil {
+		t.Fatalf("get app after writing children error: %v", err)
+	}
+	if got.ID != app.ID || got.State != types.AppNormal {
+		t.Fatalf("app overwritten by writing children: %+v", got)
+	}
+	// nor recreate the removed app
+	if err := s.DeleteApp(app.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateTask(app.ID, &types.Task{ID: "zk-test-task", AppID: app.ID}); err != ErrNotFound {
+		t.Fatalf("expect not found writing the task of removed app, got %v", err)
+	}
+	if _, err := s.GetApp(app.ID); err != ErrNotFound {
+		t.Fatalf("removed app recreated by writing children: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package types
+
+// agent maintenance states
+const (
+	AgentActive      = "active"      // tasks could be placed on the agent
+	AgentBlacklisted = "blacklisted" // no more tasks placed, existing tasks untouched
+	AgentDraining    = "draining"    // no more tasks placed, existing tasks being migrated off
+	AgentDrained     = "drained"     // no more tasks placed, all tasks migrated off
+)
+
+// Agent represents a mesos agent with swan's maintenance state
+type Agent struct {
+	ID        string            `json:"id"`
+	Hostname  string            `json:"hostname,omitempty"`
+	State     string            `json:"state"`
+	UpdatedAt int64             `json:"updatedAt,omitempty"`
+	Attrs     map[string]string `json:"attributes,omitempty"`
+
+	// runtime fields
+	Tasks        int     `json:"tasks"`
+	OfferedCpus  float64 `json:"offeredCpus"`
+	OfferedMem   float64 `json:"offeredMem"`
+	OfferedDisk  float64 `json:"offeredDisk"`
+	OfferedPorts int     `json:"offeredPorts"`
+	CachedOffers int     `json:"cachedOffers"`
+	Schedulable  bool    `json:"schedulable"`
+}
+
+// AgentMaintenance represents the persisted maintenance state of an agent,
+// the agents without maintenance state are active.
+type AgentMaintenance struct {
+	ID        string `json:"id"`
+	Hostname  string `json:"hostname,omitempty"`
+	State     string `json:"state"`
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+}
@@ -3,35 +3,100 @@
 // most of these definations are copied from original swan store/structs.go
 package types
 
+import "encoding/json"
+
 // App ...
 type App struct {
-	ID        string `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	ClusterID string `json:"clusterId,omitempty"`
-	CreatedAt int64  `json:"createdAt,omitempty"`
-	UpdatedAt int64  `json:"updatedAt,omitempty"`
-	State     string `json:"state,omitempty"`
-	// StateMachine *StateMachine `json:"stateMachine,omitempty"`
+	ID        string   `json:"id,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	ClusterID string   `json:"clusterId,omitempty"`
+	CreatedAt int64    `json:"createdAt,omitempty"`
+	UpdatedAt int64    `json:"updatedAt,omitempty"`
+	State     string   `json:"state,omitempty"` // see state.go
+	Backoff   *Backoff `json:"backoff,omitempty"`
+	ErrMsg    string   `json:"errmsg,omitempty"` // error message of the last failed operation
+
+	Progress *UpdateProgress `json:"progress,omitempty"` // progress of the ongoing update
+	Job      *JobStatus      `json:"job,omitempty"`      // completion status of the job app
 
 	// app settings
 	Version         *AppVersion `json:"version,omitempty"`
 	ProposedVersion *AppVersion `json:"proposedVersion,omitempty"`
 }
 
+// ObjectID return the id of the app, compose instance or cron job by it's name and
+// run as user in the cluster, in the form of `name.runAs.cluster`. it's not joined by
+// `-` which is allowed in the names, the separator `.` is never allowed in the names,
+// so the ids of the different names never collide.
+func ObjectID(name, runAs, cluster string) string {
+	return name + "." + runAs + "." + cluster
+}
+
+// Backoff represents the relaunch backoff status of the app's failing tasks
+type Backoff struct {
+	Failures    int    `json:"failures"`  // consecutive failures
+	Failovers   int    `json:"failovers"` // total relaunches of the failed tasks
+	Delay       string `json:"delay,omitempty"`
+	Until       int64  `json:"until,omitempty"` // relaunches are delayed until (unix nano)
+	LastReason  string `json:"lastReason,omitempty"`
+	LastMessage string `json:"lastMessage,omitempty"`
+}
+
+// UpdateProgress represents the progress of rolling the app to the proposed version
+type UpdateProgress struct {
+	Strategy   string `json:"strategy"`
+	Total      int    `json:"total"`     // desired instances of the proposed version
+	Launched   int    `json:"launched"`  // launched instances of the proposed version
+	Ready      int    `json:"ready"`     // running and healthy instances of the proposed version
+	Remaining  int    `json:"remaining"` // instances of the previous version remaining
+	Step       int    `json:"step"`      // nb of instances replaced at a time
+	Failures   int    `json:"failures"`  // nb of failed instances of the proposed version
+	StartedAt  int64  `json:"startedAt"`
+	SoakUntil  int64  `json:"soakUntil,omitempty"`  // the canary is promoted automatically at
+	Active     string `json:"active,omitempty"`     // the blue/green set serving the traffic
+	DrainUntil int64  `json:"drainUntil,omitempty"` // the blue tasks are killed at
+	Message    string `json:"message,omitempty"`
+}
+
 // AppWrapper is only for display, it wraps `App` with more useful fields.
 // TODO sigh, for compatibility, should keep same as original swan types/app.go
 type AppWrapper struct {
 	*App
-	//UpdatedInstances int      `json:"updatedInstances"`
-	//RunningInstances int      `json:"runningInstances"`
-	//Tasks            []*Task  `json:"tasks,omitempty"`
-	//Versions         []string `json:"versions,omitempty"`
+	UpdatedInstances int      `json:"updatedInstances"` // alive instances of the current version
+	RunningInstances int      `json:"runningInstances"`
+	HealthyInstances int      `json:"healthyInstances"` // running and not unhealthy instances
+	StagedInstances  int      `json:"stagedInstances"`  // staging or starting instances
+	Tasks            []*Task  `json:"tasks,omitempty"`
+	Versions         []string `json:"versions,omitempty"` // version ids, newest first
+}
+
+// NewAppWrapper wrap the app with the instance counts computed from it's tasks
+func NewAppWrapper(app *App, tasks []*Task) *AppWrapper {
+	w := &AppWrapper{App: app}
+	for _, t := range tasks {
+		switch t.State {
+		case "TASK_RUNNING":
+			w.RunningInstances++
+			if t.Healthy != "unhealthy" {
+				w.HealthyInstances++
+			}
+		case "TASK_STAGING", "TASK_STARTING":
+			w.StagedInstances++
+		default:
+			continue
+		}
+		if app.Version != nil && t.VersionID == app.Version.ID {
+			w.UpdatedInstances++
+		}
+	}
+	return w
 }
 
 // AppVersion ...
 type AppVersion struct {
-	//ID           string            `json:"id,omitempty"`
-	//AppID        string            `json:"appID,omitempty"`
+	ID           string            `json:"id,omitempty"`
+	AppID        string            `json:"appID,omitempty"`
+	CreatedAt    int64             `json:"createdAt,omitempty"`
 	AppName      string            `json:"appName,omitempty"`
 	AppVersion   string            `json:"appVersion,omitempty"`
 	Command      string            `json:"command,omitempty"`
@@ -50,8 +115,69 @@ type AppVersion struct {
 	Constraints  string            `json:"constraints,omitempty"`
 	Uris         []string          `json:"uris,omitempty"`
 	IP           []string          `json:"ip,omitempty"`
-	Mode         string            `json:"mode,omitempty"`
+	Mode         string            `json:"mode,omitempty"` // replicates (default), fixed, daemon
 	Priority     int32             `json:"priority,omitempty"`
+	Kind         string            `json:"kind,omitempty"` // service (default), job
+	Job          *JobPolicy        `json:"job,omitempty"`
+}
+
+// app kinds
+const (
+	KindService = "service" // long running tasks, relaunched once gone
+	KindJob     = "job"     // tasks run to completion, not relaunched once finished
+)
+
+// IsJob check if the app version is of job kind
+func (v *AppVersion) IsJob() bool {
+	return v.Kind == KindJob
+}
+
+// IsDaemon check if the app version runs one instance on every matching agent,
+// the instances of the daemon app is derived from the matching agents.
+func (v *AppVersion) IsDaemon() bool {
+	return v.Mode == "daemon"
+}
+
+// Copy return a deep copy of the app version
+func (v *AppVersion) Copy() (*AppVersion, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret AppVersion
+	if err := json.Unmarshal(bs, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// JobPolicy ...
+// the instances of the job app is derived from the parallelism, the job succeeds
+// once the tasks finished `Completions` times, and fails once the tasks failed
+// more than `BackoffLimit` times or the job runs beyond the active deadline.
+type JobPolicy struct {
+	Completions           int32 `json:"completions,omitempty"`           // nb of tasks to be finished, default 1
+	Parallelism           int32 `json:"parallelism,omitempty"`           // max nb of tasks running at a time, default 1
+	BackoffLimit          int32 `json:"backoffLimit,omitempty"`          // nb of retries on the task failures
+	ActiveDeadlineSeconds int64 `json:"activeDeadlineSeconds,omitempty"` // 0 means no deadline
+}
+
+// job status
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobStatus represents the completion status of the job app
+type JobStatus struct {
+	Status      string `json:"status"`
+	Succeeded   int32  `json:"succeeded"` // nb of finished tasks
+	Failed      int32  `json:"failed"`    // nb of failed tasks
+	StartedAt   int64  `json:"startedAt"`
+	CompletedAt int64  `json:"completedAt,omitempty"`
+	Message     string `json:"message,omitempty"`
 }
 
 // Container ...
@@ -99,10 +225,30 @@ type KillPolicy struct {
 
 // UpdatePolicy ...
 type UpdatePolicy struct {
-	UpdateDelay  int32  `json:"updateDelay,omitempty"`
-	MaxRetries   int32  `json:"maxRetries,omitempty"`
-	MaxFailovers int32  `json:"maxFailovers,omitempty"`
-	Action       string `json:"action,omitempty"`
+	Strategy     string        `json:"strategy,omitempty"`    // rolling (default), canary, bluegreen
+	Step         int32         `json:"step,omitempty"`        // nb of instances replaced at a time on rolling update, default 1
+	UpdateDelay  int32         `json:"updateDelay,omitempty"` // seconds to wait between the rolling update steps
+	MaxRetries   int32         `json:"maxRetries,omitempty"`
+	MaxFailovers int32         `json:"maxFailovers,omitempty"`
+	Action       string        `json:"action,omitempty"` // action on rolling update failure: stop (default), rollback
+	Canary       *CanaryPolicy `json:"canary,omitempty"`
+	BlueGreen    *BlueGreen    `json:"blueGreen,omitempty"`
+}
+
+// CanaryPolicy ...
+// the proposed version's `Gateway.Weight` percent of traffic is routed to the canary
+// tasks if set, otherwise the traffic is evenly shared by all of the tasks.
+type CanaryPolicy struct {
+	Instances   int32 `json:"instances"`             // nb of canary tasks launched alongside the current tasks
+	SoakSeconds int32 `json:"soakSeconds,omitempty"` // promote automatically after soaked so long without failures, 0 to promote manually
+}
+
+// BlueGreen ...
+// a full set of green tasks of the proposed version is launched alongside the blue
+// tasks without traffic, once all of them are healthy, the traffic is switched to
+// the green tasks at once, the blue tasks are killed after the drain period.
+type BlueGreen struct {
+	DrainSeconds int32 `json:"drainSeconds,omitempty"` // keep the blue tasks so long after switched, for switching back
 }
 
 // Gateway ...
@@ -134,6 +280,7 @@ type Task struct {
 	AppID         string   `json:"appId,omitempty"`
 	VersionID     string   `json:"versionId,omitempty"`
 	State         string   `json:"state,omitempty"`
+	Healthy       string   `json:"healthy,omitempty"`
 	Stdout        string   `json:"stdout,omitempty"`
 	Stderr        string   `json:"stderr,omitempty"`
 	HostPorts     []uint64 `json:"hostPorts,omitempty"`
@@ -147,6 +294,6 @@ type Task struct {
 	ArchivedAt    int64    `json:"archivedAt,omitempty"`
 	ContainerID   string   `json:"containerId,omitempty"`
 	ContainerName string   `json:"containerName,omitempty"`
-	Weight        float64  `json:"weight,omitempty"`
+	Weight        float64  `json:"weight,omitempty"` // relative traffic weight, 0 means no traffic
 	//SlotID        string   `json:"slotId,omitempty"`
 }
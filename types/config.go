@@ -3,6 +3,7 @@ package types
 import (
 	"fmt"
 	"net/url"
+	"time"
 )
 
 // MgrConfig represents manager configs
@@ -10,6 +11,12 @@ type MgrConfig struct {
 	Listen   string   `json:"listen"`
 	MesosURL *url.URL `json:"mesos"` // mesos zk addr
 	ZKURL    *url.URL `json:"zk"`    // swan zk store addr, if null, use memory store
+
+	Preemption  bool          `json:"preemption"`  // allow higher priority tasks to preempt lower priority ones
+	BackoffBase time.Duration `json:"backoffBase"` // base delay of relaunching the failed tasks
+	BackoffMax  time.Duration `json:"backoffMax"`  // max delay of relaunching the failed tasks
+
+	VersionRetention int `json:"versionRetention"` // nb of versions kept per app
 }
 
 // Valid verify the manager configs
@@ -22,6 +29,14 @@ func (c *MgrConfig) Valid() error {
 		return fmt.Errorf("mesos zk url invalid: %v", err)
 	}
 
+	if c.BackoffBase <= 0 || c.BackoffMax < c.BackoffBase {
+		return fmt.Errorf("backoff delay invalid: base %s, max %s", c.BackoffBase, c.BackoffMax)
+	}
+
+	if c.VersionRetention < 1 {
+		return fmt.Errorf("version retention should be at least 1")
+	}
+
 	if p := c.ZKURL; p != nil {
 		if err := validZKURL(p); err != nil {
 			return fmt.Errorf("swan zk url invalid: %v", err)
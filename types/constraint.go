@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Constraint represents a placement constraint against agent's hostname or attributes
+//
+// syntax: `field op value`, multiple constraints are separated by `,`
+// supported operators:
+//
+//	==   equal
+//	!=   not equal
+//	~=   regexp match
+//	!~   regexp not match
+//
+// eg: `hostname==node1,zone!=z1,rack~=r[0-9]+`
+type Constraint struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+
+	reg *regexp.Regexp
+}
+
+// operators should be checked in this order, as `==` is the suffix of `!=`
+var constraintOps = []string{"!=", "!~", "~=", "=="}
+
+// ParseConstraints parse the constraints expression
+func ParseConstraints(expr string) ([]*Constraint, error) {
+	ret := make([]*Constraint, 0)
+
+	for _, field := range strings.Split(expr, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		c, err := parseConstraint(field)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+
+	return ret, nil
+}
+
+func parseConstraint(expr string) (*Constraint, error) {
+	for _, op := range constraintOps {
+		idx := strings.Index(expr, op)
+		if idx < 0 {
+			continue
+		}
+
+		c := &Constraint{
+			Field: strings.TrimSpace(expr[:idx]),
+			Op:    op,
+			Value: strings.TrimSpace(expr[idx+len(op):]),
+		}
+		if c.Field == "" {
+			return nil, fmt.Errorf("constraint %q: field required", expr)
+		}
+
+		if op == "~=" || op == "!~" {
+			reg, err := regexp.Compile("^(" + c.Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("constraint %q: invalid regexp: %v", expr, err)
+			}
+			c.reg = reg
+		}
+
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("constraint %q: no valid operator, should be one of %v", expr, constraintOps)
+}
+
+// Match check the constraint against the field value, exists indicates
+// whether the agent has the field, a missing field only satisfies negative operators.
+func (c *Constraint) Match(value string, exists bool) bool {
+	switch c.Op {
+	case "==":
+		return exists && value == c.Value
+	case "!=":
+		return !exists || value != c.Value
+	case "~=":
+		return exists && c.reg.MatchString(value)
+	case "!~":
+		return !exists || !c.reg.MatchString(value)
+	}
+	return false
+}
+
+// String ...
+func (c *Constraint) String() string {
+	return c.Field + c.Op + c.Value
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2017, 3, 10, 10, 20, 30, 0, time.UTC) // friday
+
+	for expr, expect := range map[string]string{
+		"* * * * *":           "2017-03-10 10:21",
+		"*/15 * * * *":        "2017-03-10 10:30",
+		"5 * * * *":           "2017-03-10 11:05",
+		"0 2 * * *":           "2017-03-11 02:00",
+		"@daily":              "2017-03-11 00:00",
+		"30 9 * * mon-fri":    "2017-03-13 09:30",
+		"0 0 1 jan *":         "2018-01-01 00:00",
+		"0 0 29 2 *":          "2020-02-29 00:00",
+		"0 12 13 * 5":         "2017-03-10 12:00", // the 13th or fridays
+		"0 12 * * 7":          "2017-03-12 12:00",
+		"10-20/5 8,18 * * *":  "2017-03-10 18:10",
+		"0 0 31 4 *":          "",
+		"59 23 31 12 sun,sat": "2017-12-02 23:59",
+	} {
+		s, err := ParseCron(expr)
+		if err != nil {
+			t.Errorf("parse %q error: %v", expr, err)
+			continue
+		}
+
+		next := s.Next(from)
+		got := ""
+		if !next.IsZero() {
+			got = next.Format("2006-01-02 15:04")
+		}
+		if got != expect {
+			t.Errorf("next of %q: expect %q, got %q", expr, expect, got)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("parse %q: expect error", expr)
+		}
+	}
+}
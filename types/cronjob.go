@@ -0,0 +1,121 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// concurrency policies of the cron job
+const (
+	ConcurrencyAllow   = "allow"   // the runs could be concurrent (default)
+	ConcurrencyForbid  = "forbid"  // skip the run if the previous run is still running
+	ConcurrencyReplace = "replace" // delete the running run before starting the new run
+)
+
+// cron job run status
+const (
+	CronRunStarted = "started" // the job app of the run is created, see the app's job status
+	CronRunSkipped = "skipped" // skipped by the concurrency policy
+	CronRunMissed  = "missed"  // not started before the starting deadline
+	CronRunFailed  = "failed"  // failed to create the job app
+)
+
+// CronJob represents a job app template launched periodically by the schedule
+type CronJob struct {
+	ID                      string      `json:"id,omitempty"`
+	Name                    string      `json:"name"`
+	RunAs                   string      `json:"runAs"`
+	ClusterID               string      `json:"clusterId,omitempty"`
+	Schedule                string      `json:"schedule"`                          // standard 5-field cron expression
+	TimeZone                string      `json:"timeZone,omitempty"`                // eg: Asia/Shanghai, default UTC
+	ConcurrencyPolicy       string      `json:"concurrencyPolicy,omitempty"`       // allow (default), forbid, replace
+	StartingDeadlineSeconds int64       `json:"startingDeadlineSeconds,omitempty"` // the run is missed if not started in time, 0 means no deadline
+	HistoryLimit            int         `json:"historyLimit,omitempty"`            // nb of runs retained, default 3
+	Template                *AppVersion `json:"template"`                          // the job app settings of the runs
+	CreatedAt               int64       `json:"createdAt,omitempty"`
+	LastScheduleTime        int64       `json:"lastScheduleTime,omitempty"` // the scheduled time of the last run
+	NextScheduleTime        int64       `json:"nextScheduleTime,omitempty"`
+	Runs                    []*CronRun  `json:"runs,omitempty"` // retained runs, oldest first
+}
+
+// CronRun represents a single run of the cron job
+type CronRun struct {
+	AppID       string `json:"appId,omitempty"`
+	ScheduledAt int64  `json:"scheduledAt"`
+	StartedAt   int64  `json:"startedAt,omitempty"`
+	Status      string `json:"status"`
+	Manual      bool   `json:"manual,omitempty"` // triggered by request
+	Message     string `json:"message,omitempty"`
+
+	Job *JobStatus `json:"job,omitempty"` // the job status of the run's app, only for display
+}
+
+// Location return the time zone of the cron job's schedule
+func (c *CronJob) Location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.TimeZone)
+}
+
+// Valid verify the cron job with it's template, it returns ValidationErrors
+// if any invalid fields. the template should be expanded.
+func (c *CronJob) Valid() error {
+	var errs ValidationErrors
+
+	// the run's app name is the cron job name with at most 15 chars suffix, see RunName
+	if !regName.MatchString(c.Name) || len(c.Name) > 33 {
+		errs.add("name", "should be lower case alphanumeric characters or '-', at most 33 chars")
+	}
+	if !regName.MatchString(c.RunAs) || len(c.RunAs) > 32 {
+		errs.add("runAs", "should be lower case alphanumeric characters or '-', at most 32 chars")
+	}
+	if _, err := ParseCron(c.Schedule); err != nil {
+		errs.add("schedule", "%v", err)
+	}
+	if _, err := c.Location(); err != nil {
+		errs.add("timeZone", "%v", err)
+	}
+
+	switch c.ConcurrencyPolicy {
+	case "", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+	default:
+		errs.add("concurrencyPolicy", "should be one of [allow forbid replace]")
+	}
+
+	if c.StartingDeadlineSeconds < 0 {
+		errs.add("startingDeadlineSeconds", "should not be negative")
+	}
+	if c.HistoryLimit < 0 {
+		errs.add("historyLimit", "should not be negative")
+	}
+
+	if c.Template == nil {
+		errs.add("template", "required")
+	} else if !c.Template.IsJob() {
+		errs.add("template.kind", "should be job")
+	} else if err := c.Template.Valid(); err != nil {
+		if ves, ok := err.(ValidationErrors); ok {
+			for _, e := range ves {
+				errs.add("template."+e.Field, "%s", e.Message)
+			}
+		} else {
+			errs.add("template", "%v", err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// RunName return the app name of the run scheduled at. the scheduled runs are
+// suffixed with the unix seconds, the manual ones with `m` and the unix millis,
+// so the manual runs never collide with the scheduled ones.
+func (c *CronJob) RunName(scheduled time.Time, manual bool) string {
+	if manual {
+		return fmt.Sprintf("%s-m%d", c.Name, scheduled.UnixNano()/int64(time.Millisecond))
+	}
+	return fmt.Sprintf("%s-%d", c.Name, scheduled.Unix())
+}
@@ -0,0 +1,141 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// change types
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// the version meta fields are not taken into account on diff
+var diffIgnored = map[string]bool{"id": true, "appID": true, "createdAt": true}
+
+// Change represents a field level change between two app versions,
+// the field is the json path, eg: `container.docker.image`, `env.FOO`, `uris[1]`
+type Change struct {
+	Field string      `json:"field"`
+	Type  string      `json:"type"`
+	From  interface{} `json:"from,omitempty"`
+	To    interface{} `json:"to,omitempty"`
+}
+
+// DiffVersions return the field level changes from version a to b, ordered by the field
+func DiffVersions(a, b *AppVersion) ([]*Change, error) {
+	ma, err := toMap(a)
+	if err != nil {
+		return nil, err
+	}
+	mb, err := toMap(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range diffIgnored {
+		delete(ma, key)
+		delete(mb, key)
+	}
+
+	ret := make([]*Change, 0)
+	diffValue("", ma, mb, &ret)
+	sort.Sort(changeSorter(ret))
+	return ret, nil
+}
+
+func toMap(ver *AppVersion) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	if ver == nil {
+		return ret, nil
+	}
+
+	bs, err := json.Marshal(ver)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bs, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func diffValue(field string, a, b interface{}, ret *[]*Change) {
+	switch {
+	case isEmpty(a) && isEmpty(b):
+		return
+	case isEmpty(a):
+		*ret = append(*ret, &Change{Field: field, Type: ChangeAdded, To: b})
+		return
+	case isEmpty(b):
+		*ret = append(*ret, &Change{Field: field, Type: ChangeRemoved, From: a})
+		return
+	}
+
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if okA && okB {
+		keys := make(map[string]bool)
+		for k := range ma {
+			keys[k] = true
+		}
+		for k := range mb {
+			keys[k] = true
+		}
+		for k := range keys {
+			diffValue(join(field, k), ma[k], mb[k], ret)
+		}
+		return
+	}
+
+	sa, okA := a.([]interface{})
+	sb, okB := b.([]interface{})
+	if okA && okB {
+		for i := 0; i < len(sa) || i < len(sb); i++ {
+			var va, vb interface{}
+			if i < len(sa) {
+				va = sa[i]
+			}
+			if i < len(sb) {
+				vb = sb[i]
+			}
+			diffValue(fmt.Sprintf("%s[%d]", field, i), va, vb, ret)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*ret = append(*ret, &Change{Field: field, Type: ChangeChanged, From: a, To: b})
+	}
+}
+
+// isEmpty check if the json decoded value is absent or empty
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// changeSorter sort changes by field
+type changeSorter []*Change
+
+func (s changeSorter) Len() int           { return len(s) }
+func (s changeSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s changeSorter) Less(i, j int) bool { return s[i].Field < s[j].Field }
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestDiffVersions(t *testing.T) {
+	a := &AppVersion{
+		ID:   "1",
+		Cpus: 0.5,
+		Mem:  128,
+		Env:  map[string]string{"FOO": "1", "BAR": "2"},
+		Uris: []string{"http://a"},
+		Container: &Container{
+			Type:   "docker",
+			Docker: &Docker{Image: "nginx:1.10"},
+		},
+	}
+	b := &AppVersion{
+		ID:   "2",
+		Cpus: 1,
+		Mem:  128,
+		Env:  map[string]string{"FOO": "1", "BAZ": "3"},
+		Uris: []string{"http://a", "http://b"},
+		Container: &Container{
+			Type:   "docker",
+			Docker: &Docker{Image: "nginx:1.11"},
+		},
+	}
+
+	changes, err := DiffVersions(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []struct {
+		field, typ string
+	}{
+		{"container.docker.image", ChangeChanged},
+		{"cpus", ChangeChanged},
+		{"env.BAR", ChangeRemoved},
+		{"env.BAZ", ChangeAdded},
+		{"uris[1]", ChangeAdded},
+	}
+
+	if len(changes) != len(expect) {
+		t.Fatalf("expect %d changes, got %d: %+v", len(expect), len(changes), changes)
+	}
+	for i, e := range expect {
+		if c := changes[i]; c.Field != e.field || c.Type != e.typ {
+			t.Errorf("change %d: expect %s %s, got %s %s", i, e.field, e.typ, c.Field, c.Type)
+		}
+	}
+}
@@ -11,15 +11,18 @@ type Event struct {
 	Status string    `json:"status"`
 	From   string    `json:"from"`
 	Time   time.Time `json:"time"`
+
+	Message string `json:"message,omitempty"` // optional details, eg: the update progress
 }
 
 // Format ...
 func (e *Event) Format() string {
-	return fmt.Sprintf("event: swan\nid: %d\ndata: {%q:%q,%q:%q,%q:%q,%q:%q}\n\n",
+	return fmt.Sprintf("event: swan\nid: %d\ndata: {%q:%q,%q:%q,%q:%q,%q:%q,%q:%q}\n\n",
 		time.Now().UnixNano(),
 		"id", e.ID,
 		"status", e.Status,
 		"from", e.From,
 		"time", e.Time,
+		"message", e.Message,
 	)
 }
@@ -1,5 +1,301 @@
 package types
 
-// Instance represents compose instance
+import (
+	"fmt"
+	"strings"
+)
+
+// compose instance states
+const (
+	InstanceCreating = "creating" // creating the member apps
+	InstanceCreated  = "created"  // all of the member apps are created
+	InstanceFailed   = "failed"   // failed to create some of the member apps
+	InstanceDeleting = "deleting" // deleting all of the member apps before removed
+)
+
+// Instance represents compose instance, a stack of apps managed as one unit
 type Instance struct {
+	ID        string     `json:"id,omitempty"`
+	Name      string     `json:"name"`
+	RunAs     string     `json:"runAs"`
+	ClusterID string     `json:"clusterId,omitempty"`
+	Desc      string     `json:"desc,omitempty"`
+	Status    string     `json:"status,omitempty"` // see instance states above
+	ErrMsg    string     `json:"errmsg,omitempty"`
+	CreatedAt int64      `json:"createdAt,omitempty"`
+	UpdatedAt int64      `json:"updatedAt,omitempty"`
+	Services  []*Service `json:"services"`
+
+	Variables map[string]string   `json:"variables,omitempty"` // interpolated into the services on deploy
+	Overlays  map[string]*Overlay `json:"overlays,omitempty"`  // named environment overlays, eg: dev, prod
+	Overlay   string              `json:"overlay,omitempty"`   // the overlay applied on deploy
+}
+
+// Overlay overrides the settings of the services for an environment
+type Overlay struct {
+	Services map[string]*ServiceOverlay `json:"services"` // by service name
+}
+
+// ServiceOverlay overrides the settings of a service, the unset fields are untouched
+type ServiceOverlay struct {
+	Instances *int32            `json:"instances,omitempty"`
+	Cpus      *float64          `json:"cpus,omitempty"`
+	Mem       *float64          `json:"mem,omitempty"`
+	Disk      *float64          `json:"disk,omitempty"`
+	Env       map[string]string `json:"env,omitempty"` // merged into the service's env
+}
+
+// Service represents a named member app of the compose instance
+type Service struct {
+	Name         string      `json:"name"`
+	AppID        string      `json:"appId,omitempty"`        // the member app, set on creation
+	DependsOn    []string    `json:"dependsOn,omitempty"`    // names of the services it depends on
+	StartTimeout int32       `json:"startTimeout,omitempty"` // seconds to wait for it's dependencies ready, default 5m
+	Version      *AppVersion `json:"version"`
+}
+
+// the separator of the instance and service names in the member app name,
+// it's rejected in the instance and service names, so the member app names never collide.
+const appNameSep = "--"
+
+// AppName return the name of the service's member app
+func (ins *Instance) AppName(svc *Service) string {
+	return ins.Name + appNameSep + svc.Name
+}
+
+// Expand fill the services' app name and run as user from the instance
+func (ins *Instance) Expand() {
+	for _, svc := range ins.Services {
+		if svc.Version == nil {
+			continue
+		}
+		svc.Version.AppName = ins.AppName(svc)
+		svc.Version.RunAs = ins.RunAs
+	}
+}
+
+// Render apply the selected overlay on the services, then interpolate the variables
+// into them if any. it should be done once before the instance deployed.
+func (ins *Instance) Render() error {
+	if ins.Overlay != "" {
+		o, ok := ins.Overlays[ins.Overlay]
+		if !ok {
+			return fmt.Errorf("no such overlay %s", ins.Overlay)
+		}
+		for name, so := range o.Services {
+			svc := ins.Service(name)
+			if svc == nil || svc.Version == nil {
+				return fmt.Errorf("overlay %s: no such service %s", ins.Overlay, name)
+			}
+			so.apply(svc.Version)
+		}
+	}
+
+	if len(ins.Variables) == 0 {
+		return nil
+	}
+	for _, svc := range ins.Services {
+		if svc.Version == nil {
+			continue
+		}
+		if err := svc.Version.Interpolate(ins.Variables); err != nil {
+			return fmt.Errorf("service %s: %v", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func (so *ServiceOverlay) apply(ver *AppVersion) {
+	if so.Instances != nil {
+		ver.Instances = *so.Instances
+	}
+	if so.Cpus != nil {
+		ver.Cpus = *so.Cpus
+	}
+	if so.Mem != nil {
+		ver.Mem = *so.Mem
+	}
+	if so.Disk != nil {
+		ver.Disk = *so.Disk
+	}
+	if len(so.Env) > 0 && ver.Env == nil {
+		ver.Env = make(map[string]string)
+	}
+	for k, v := range so.Env {
+		ver.Env[k] = v
+	}
+}
+
+// Service return the named service, nil if not found
+func (ins *Instance) Service(name string) *Service {
+	for _, svc := range ins.Services {
+		if svc.Name == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+// Order return the services in the dependency order, each service is after
+// all of the services it depends on, otherwise the services keep the defined
+// order. it returns error if the dependencies are circular.
+func (ins *Instance) Order() ([]*Service, error) {
+	var (
+		ret     = make([]*Service, 0, len(ins.Services))
+		visited = make(map[string]bool)
+	)
+
+	for len(ret) < len(ins.Services) {
+		var progress bool
+		for _, svc := range ins.Services {
+			if visited[svc.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range svc.DependsOn {
+				if !visited[dep] && ins.Service(dep) != nil {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			visited[svc.Name] = true
+			ret = append(ret, svc)
+			progress = true
+		}
+
+		if !progress {
+			names := make([]string, 0)
+			for _, svc := range ins.Services {
+				if !visited[svc.Name] {
+					names = append(names, svc.Name)
+				}
+			}
+			return nil, fmt.Errorf("circular dependencies among services %v", names)
+		}
+	}
+
+	return ret, nil
+}
+
+// Valid verify the compose instance with all of it's services, it returns
+// ValidationErrors if any invalid fields. the services should be expanded.
+func (ins *Instance) Valid() error {
+	var errs ValidationErrors
+
+	if !regName.MatchString(ins.Name) || len(ins.Name) > 32 {
+		errs.add("name", "should be lower case alphanumeric characters or '-', at most 32 chars")
+	} else if strings.Contains(ins.Name, appNameSep) {
+		errs.add("name", "should not contain %q", appNameSep)
+	}
+	if !regName.MatchString(ins.RunAs) || len(ins.RunAs) > 32 {
+		errs.add("runAs", "should be lower case alphanumeric characters or '-', at most 32 chars")
+	}
+	if len(ins.Services) == 0 {
+		errs.add("services", "at least one service required")
+	}
+
+	names := make(map[string]bool)
+	for i, svc := range ins.Services {
+		field := fmt.Sprintf("services[%d]", i)
+		if !regName.MatchString(svc.Name) {
+			errs.add(field+".name", "should be lower case alphanumeric characters or '-'")
+		} else if strings.Contains(svc.Name, appNameSep) {
+			errs.add(field+".name", "should not contain %q", appNameSep)
+		}
+		if names[svc.Name] {
+			errs.add(field+".name", "duplicated service name %s", svc.Name)
+		}
+		names[svc.Name] = true
+	}
+
+	for i, svc := range ins.Services {
+		field := fmt.Sprintf("services[%d]", i)
+		for _, dep := range svc.DependsOn {
+			if dep == svc.Name || ins.Service(dep) == nil {
+				errs.add(field+".dependsOn", "no such service %s", dep)
+			}
+		}
+		if svc.StartTimeout < 0 {
+			errs.add(field+".startTimeout", "should not be negative")
+		}
+
+		if svc.Version == nil {
+			errs.add(field+".version", "required")
+			continue
+		}
+		if err := svc.Version.Valid(); err != nil {
+			if ves, ok := err.(ValidationErrors); ok {
+				for _, e := range ves {
+					errs.add(field+".version."+e.Field, "%s", e.Message)
+				}
+			} else {
+				errs.add(field+".version", "%v", err)
+			}
+		}
+	}
+
+	if _, err := ins.Order(); err != nil {
+		errs.add("services", "%v", err)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// InstanceWrapper is only for display, it wraps `Instance` with the member apps' states.
+type InstanceWrapper struct {
+	*Instance
+	State string           `json:"state"` // aggregated state of the member apps
+	Apps  []*ServiceStatus `json:"apps"`
+}
+
+// ServiceStatus represents the status of the service's member app
+type ServiceStatus struct {
+	Service          string `json:"service"`
+	AppID            string `json:"appId"`
+	State            string `json:"state"` // app state, or missing if not found
+	Instances        int32  `json:"instances"`
+	RunningInstances int    `json:"runningInstances"`
+	ErrMsg           string `json:"errmsg,omitempty"`
+}
+
+// AggregateState return the overall state of the member apps: the state of all
+// the apps if they're in the same one, `degraded` if some of them are missing,
+// failed or crash looping, then the ongoing operations, and `partially_suspended`
+// if some of the rest are suspended.
+func AggregateState(states []string) string {
+	if len(states) == 0 {
+		return ""
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range states {
+		seen[s] = true
+	}
+
+	if len(seen) == 1 {
+		return states[0]
+	}
+
+	if seen["missing"] || seen[AppFailed] || seen[AppCrashLooping] {
+		return "degraded"
+	}
+
+	for _, s := range []string{AppDeleting, AppCreating, AppUpdating, AppCanary, AppScaling} {
+		if seen[s] {
+			return s
+		}
+	}
+
+	if seen[AppSuspended] {
+		return "partially_" + AppSuspended // normal and suspended apps mixed
+	}
+	return AppNormal
 }
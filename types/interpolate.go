@@ -0,0 +1,306 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Interpolate substitute the variables in the text
+//
+// syntax:
+//
+//	$VAR or ${VAR}     the value of VAR, error if not set
+//	${VAR:-default}    the default if VAR not set or empty
+//	${VAR-default}     the default if VAR not set
+//	${VAR:?message}    error with the message if VAR not set or empty
+//	$$                 the literal $
+func Interpolate(text string, vars map[string]string) (string, error) {
+	var (
+		buf  = make([]byte, 0, len(text))
+		rest = text
+	)
+
+	for {
+		i := strings.IndexByte(rest, '$')
+		if i < 0 || i == len(rest)-1 {
+			buf = append(buf, rest...)
+			break
+		}
+		buf = append(buf, rest[:i]...)
+		rest = rest[i+1:]
+
+		switch {
+		case rest[0] == '$':
+			buf = append(buf, '$')
+			rest = rest[1:]
+
+		case rest[0] == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed variable in %q", text)
+			}
+			val, err := expand(rest[1:end], vars)
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, val...)
+			rest = rest[end+1:]
+
+		default:
+			n := 0
+			for n < len(rest) && isVarChar(rest[n], n == 0) {
+				n++
+			}
+			if n == 0 {
+				buf = append(buf, '$') // not a variable
+				continue
+			}
+			val, err := expand(rest[:n], vars)
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, val...)
+			rest = rest[n:]
+		}
+	}
+
+	return string(buf), nil
+}
+
+// expand return the value of the variable expression within the braces
+func expand(expr string, vars map[string]string) (string, error) {
+	name := expr
+	for n := 0; n < len(expr); n++ {
+		if !isVarChar(expr[n], n == 0) {
+			name = expr[:n]
+			break
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid variable ${%s}", expr)
+	}
+
+	var (
+		op      = expr[len(name):]
+		val, ok = vars[name]
+	)
+	switch {
+	case op == "":
+		if !ok {
+			return "", fmt.Errorf("variable %s not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(op, ":-"):
+		if val == "" {
+			return op[2:], nil
+		}
+		return val, nil
+	case strings.HasPrefix(op, "-"):
+		if !ok {
+			return op[1:], nil
+		}
+		return val, nil
+	case strings.HasPrefix(op, ":?"):
+		if val == "" {
+			return "", fmt.Errorf("variable %s: %s", name, op[2:])
+		}
+		return val, nil
+	}
+
+	return "", fmt.Errorf("invalid variable ${%s}", expr)
+}
+
+func isVarChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
+
+// Interpolate substitute the variables in all of the string settings of the version,
+// including the values of the labels and the env.
+func (v *AppVersion) Interpolate(vars map[string]string) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(bs, &doc); err != nil {
+		return err
+	}
+
+	if doc, err = interpolateValue(doc, vars); err != nil {
+		return err
+	}
+
+	if bs, err = json.Marshal(doc); err != nil {
+		return err
+	}
+
+	var ret AppVersion
+	if err := json.Unmarshal(bs, &ret); err != nil {
+		return err
+	}
+	*v = ret
+	return nil
+}
+
+// interpolateValue substitute the variables in the string leaves of the decoded json
+func interpolateValue(v interface{}, vars map[string]string) (interface{}, error) {
+	var err error
+
+	switch val := v.(type) {
+	case string:
+		return Interpolate(val, vars)
+
+	case []interface{}:
+		for i := range val {
+			if val[i], err = interpolateValue(val[i], vars); err != nil {
+				return nil, err
+			}
+		}
+
+	case map[string]interface{}:
+		for k := range val {
+			if val[k], err = interpolateValue(val[k], vars); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v, nil
+}
+
+// DecodeInterpolated substitute the variables in the string leaves of the raw json
+// data, then decode it into v. the substituted strings are converted to the numbers
+// or bools if v expects so, eg: `"instances": "${N}"`.
+func DecodeInterpolated(data []byte, vars map[string]string, v interface{}) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	doc, err := interpolateTyped(doc, reflect.TypeOf(v), vars)
+	if err != nil {
+		return err
+	}
+
+	bs, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
+
+// interpolateTyped substitute the variables in the string leaves of the decoded json
+// like interpolateValue, and convert the substituted strings to the scalars of type t.
+func interpolateTyped(v interface{}, t reflect.Type, vars map[string]string) (interface{}, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return interpolateValue(v, vars)
+	}
+
+	var err error
+
+	switch val := v.(type) {
+	case string:
+		text, err := Interpolate(val, vars)
+		if err != nil {
+			return nil, err
+		}
+		switch t.Kind() {
+		case reflect.String, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+			return text, nil
+		}
+		if !strings.Contains(val, "$") {
+			return text, nil
+		}
+		ptr := reflect.New(t)
+		if err := json.Unmarshal([]byte(text), ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("%q: expect %s: %v", text, t.Kind(), err)
+		}
+		return ptr.Elem().Interface(), nil
+
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return interpolateValue(v, vars)
+		}
+		for i := range val {
+			if val[i], err = interpolateTyped(val[i], t.Elem(), vars); err != nil {
+				return nil, err
+			}
+		}
+
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for k := range val {
+				if val[k], err = interpolateTyped(val[k], t.Elem(), vars); err != nil {
+					return nil, err
+				}
+			}
+		case reflect.Struct:
+			if err := interpolateFields(val, t, vars); err != nil {
+				return nil, err
+			}
+		default:
+			return interpolateValue(v, vars)
+		}
+	}
+
+	return v, nil
+}
+
+// interpolateFields interpolate the json object by the fields of the struct type t,
+// the keys not known by t are interpolated as they are.
+func interpolateFields(obj map[string]interface{}, t reflect.Type, vars map[string]string) error {
+	fields := make(map[string]reflect.Type)
+	collectFields(t, fields)
+
+	for k := range obj {
+		var err error
+		if obj[k], err = interpolateTyped(obj[k], fields[k], vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectFields collect the json names of the struct type's fields with their types
+func collectFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, fields)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{
+		"TAG":   "1.11",
+		"EMPTY": "",
+	}
+
+	for text, expect := range map[string]string{
+		"nginx:${TAG}":              "nginx:1.11",
+		"nginx:$TAG":                "nginx:1.11",
+		"${MISSING:-latest}":        "latest",
+		"${EMPTY:-latest}":          "latest",
+		"${EMPTY-latest}":           "",
+		"${TAG:-latest}-${TAG-x}":   "1.11-1.11",
+		"cost $$5, $1 and $":        "cost $5, $1 and $",
+		"no variables":              "no variables",
+		"${MISSING:-a b}/${TAG}/$$": "a b/1.11/$",
+	} {
+		got, err := Interpolate(text, vars)
+		if err != nil {
+			t.Errorf("interpolate %q error: %v", text, err)
+			continue
+		}
+		if got != expect {
+			t.Errorf("interpolate %q: expect %q, got %q", text, expect, got)
+		}
+	}
+
+	for _, text := range []string{"$MISSING", "${MISSING}", "${EMPTY:?required}", "${TAG", "${-x}"} {
+		if _, err := Interpolate(text, vars); err == nil {
+			t.Errorf("interpolate %q: expect error", text)
+		}
+	}
+}
+
+func TestDecodeInterpolated(t *testing.T) {
+	var (
+		data = []byte(`{"appName": "${NAME}", "instances": "${N}", "cpus": "${CPU:-0.5}", "env": {"N": "${N}"}}`)
+		vars = map[string]string{"NAME": "web", "N": "3"}
+		ver  AppVersion
+	)
+
+	if err := DecodeInterpolated(data, vars, &ver); err != nil {
+		t.Fatalf("decode interpolated error: %v", err)
+	}
+	if ver.AppName != "web" || ver.Instances != 3 || ver.Cpus != 0.5 || ver.Env["N"] != "3" {
+		t.Fatalf("unexpected decoded version: %+v", ver)
+	}
+
+	vars["N"] = "three"
+	if err := DecodeInterpolated(data, vars, &ver); err == nil {
+		t.Fatalf("expect error decoding non-numeric instances")
+	}
+}
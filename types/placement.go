@@ -0,0 +1,17 @@
+package types
+
+// DryRunResult represents the placement evaluation of an app version
+// against the currently cached offers and agents.
+type DryRunResult struct {
+	Instances  int                  `json:"instances"`
+	Placeable  int                  `json:"placeable"`
+	Placements []*InstancePlacement `json:"placements"`
+}
+
+// InstancePlacement represents the placement evaluation of a single instance
+type InstancePlacement struct {
+	Index      int               `json:"index"`
+	Candidates []string          `json:"candidates,omitempty"` // agents could host the instance
+	Chosen     string            `json:"chosen,omitempty"`     // the agent would be chosen
+	Failures   map[string]string `json:"failures,omitempty"`   // agent id -> unplaceable reason
+}
@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var regSelectorKey = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_./-]*[a-zA-Z0-9])?$`)
+
+// Selector represents a label selector, all of the requirements should be matched
+//
+// syntax: requirements separated by `,`
+//
+//	key=value    the label equals to value (`==` is also accepted)
+//	key!=value   the label not exists or not equals to value
+//	key          the label exists
+//	!key         the label not exists
+//
+// eg: `env=prod,tier!=db,canary`
+type Selector []*Requirement
+
+// Requirement represents a single requirement of the label selector
+type Requirement struct {
+	Key   string `json:"key"`
+	Op    string `json:"op"` // =, !=, exists, !exists
+	Value string `json:"value,omitempty"`
+}
+
+// ParseSelector parse the label selector expression
+func ParseSelector(expr string) (Selector, error) {
+	ret := make(Selector, 0)
+
+	for _, field := range strings.Split(expr, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		r := new(Requirement)
+		switch {
+		case strings.Contains(field, "!="):
+			kv := strings.SplitN(field, "!=", 2)
+			r.Key, r.Op, r.Value = kv[0], "!=", kv[1]
+		case strings.Contains(field, "=="):
+			kv := strings.SplitN(field, "==", 2)
+			r.Key, r.Op, r.Value = kv[0], "=", kv[1]
+		case strings.Contains(field, "="):
+			kv := strings.SplitN(field, "=", 2)
+			r.Key, r.Op, r.Value = kv[0], "=", kv[1]
+		case strings.HasPrefix(field, "!"):
+			r.Key, r.Op = field[1:], "!exists"
+		default:
+			r.Key, r.Op = field, "exists"
+		}
+
+		r.Key, r.Value = strings.TrimSpace(r.Key), strings.TrimSpace(r.Value)
+		if !regSelectorKey.MatchString(r.Key) {
+			return nil, fmt.Errorf("selector %q: invalid label key %q", field, r.Key)
+		}
+
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// Matches check if the labels match all of the requirements
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		val, ok := labels[r.Key]
+		switch r.Op {
+		case "=":
+			if !ok || val != r.Value {
+				return false
+			}
+		case "!=":
+			if ok && val == r.Value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// String ...
+func (s Selector) String() string {
+	ss := make([]string, 0, len(s))
+	for _, r := range s {
+		switch r.Op {
+		case "exists":
+			ss = append(ss, r.Key)
+		case "!exists":
+			ss = append(ss, "!"+r.Key)
+		default:
+			ss = append(ss, r.Key+r.Op+r.Value)
+		}
+	}
+	return strings.Join(ss, ",")
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"fmt"
+)
+
+// app states
+const (
+	AppCreating     = "creating"      // launching the initial instances
+	AppNormal       = "normal"        // all of the desired instances are running
+	AppScaling      = "scaling"       // scaling to the desired nb of instances
+	AppUpdating     = "updating"      // rolling to the proposed version, or rolling back
+	AppCanary       = "canary"        // the canary tasks of the proposed version are soaking
+	AppCrashLooping = "crash_looping" // the tasks keep failing, relaunching with backoff
+	AppSuspended    = "suspended"     // all of the tasks are killed, could be resumed
+	AppFailed       = "failed"        // gave up relaunching the failed tasks, or the update failed
+	AppDeleting     = "deleting"      // killing all of the tasks before removed
+)
+
+// appTransitions defines the allowed app state transitions,
+// staying in the same state is always allowed.
+// the creating or scaling app could be updated, eg: to fix the unplaceable settings.
+// the updating or canary app never becomes crash looping, the ongoing update owns
+// the app state and counts the failures of the new tasks itself.
+var appTransitions = map[string][]string{
+	AppCreating:     {AppNormal, AppScaling, AppUpdating, AppCrashLooping, AppFailed, AppSuspended, AppDeleting},
+	AppNormal:       {AppScaling, AppUpdating, AppCanary, AppCrashLooping, AppFailed, AppSuspended, AppDeleting},
+	AppScaling:      {AppNormal, AppUpdating, AppCrashLooping, AppFailed, AppSuspended, AppDeleting},
+	AppUpdating:     {AppNormal, AppFailed, AppDeleting},
+	AppCanary:       {AppUpdating, AppNormal, AppFailed, AppDeleting},
+	AppCrashLooping: {AppCreating, AppNormal, AppScaling, AppUpdating, AppCanary, AppFailed, AppSuspended, AppDeleting},
+	AppFailed:       {AppNormal, AppScaling, AppUpdating, AppCanary, AppCrashLooping, AppSuspended, AppDeleting},
+	AppSuspended:    {AppCreating, AppNormal, AppDeleting},
+	AppDeleting:     {},
+}
+
+// StateError represents the app could not transit to the state
+type StateError struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Error implement error
+func (e *StateError) Error() string {
+	return fmt.Sprintf("app is %s, could not be %s", e.From, e.To)
+}
+
+// CheckTransition check if the app is allowed to transit between the states
+func CheckTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	for _, s := range appTransitions[from] {
+		if s == to {
+			return nil
+		}
+	}
+
+	return &StateError{From: from, To: to}
+}
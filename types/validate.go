@@ -0,0 +1,302 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	regName     = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	regLabelKey = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_./-]*[a-zA-Z0-9])?$`)
+	regEnvKey   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// FieldError represents a validation error on the specified field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors represents all of the field errors of a validation
+type ValidationErrors []*FieldError
+
+// Error implement error
+func (es ValidationErrors) Error() string {
+	ss := make([]string, 0, len(es))
+	for _, e := range es {
+		ss = append(ss, e.Field+": "+e.Message)
+	}
+	return strings.Join(ss, "; ")
+}
+
+func (es *ValidationErrors) add(field, format string, args ...interface{}) {
+	*es = append(*es, &FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Valid verify the app version settings, it returns ValidationErrors if any invalid fields
+func (v *AppVersion) Valid() error {
+	var errs ValidationErrors
+
+	if !regName.MatchString(v.AppName) || len(v.AppName) > 48 {
+		errs.add("appName", "should be lower case alphanumeric characters or '-', at most 48 chars")
+	}
+	if !regName.MatchString(v.RunAs) || len(v.RunAs) > 32 {
+		errs.add("runAs", "should be lower case alphanumeric characters or '-', at most 32 chars")
+	}
+
+	// resources
+	if v.Cpus < 0.01 {
+		errs.add("cpus", "should be at least 0.01")
+	}
+	if v.Mem < 4 {
+		errs.add("mem", "should be at least 4 (MB)")
+	}
+	if v.Disk < 0 {
+		errs.add("disk", "should not be negative")
+	}
+	if v.Instances < 0 {
+		errs.add("instances", "should not be negative")
+	}
+
+	switch v.Mode {
+	case "", "replicates":
+	case "fixed":
+		if len(v.IP) != int(v.Instances) {
+			errs.add("ip", "should provide %d ips for fixed mode, got %d", v.Instances, len(v.IP))
+		}
+	case "daemon":
+		if len(v.IP) > 0 {
+			errs.add("ip", "not allowed for daemon mode")
+		}
+	default:
+		errs.add("mode", "should be one of [replicates fixed daemon]")
+	}
+
+	if v.Command == "" && v.Container == nil {
+		errs.add("command", "command or container required")
+	}
+
+	v.validContainer(&errs)
+	v.validHealthCheck(&errs)
+
+	if _, err := ParseConstraints(v.Constraints); err != nil {
+		errs.add("constraints", "%v", err)
+	}
+
+	for key, val := range v.Labels {
+		if !regLabelKey.MatchString(key) || len(key) > 63 {
+			errs.add("labels."+key, "key should be alphanumeric characters or '-_./', at most 63 chars")
+		}
+		if len(val) > 255 {
+			errs.add("labels."+key, "value should be at most 255 chars")
+		}
+	}
+
+	for key := range v.Env {
+		if !regEnvKey.MatchString(key) {
+			errs.add("env."+key, "should be a valid environment variable name")
+		}
+	}
+
+	if p := v.KillPolicy; p != nil && p.Duration < 0 {
+		errs.add("killPolicy.duration", "should not be negative")
+	}
+
+	if p := v.UpdatePolicy; p != nil {
+		if p.Step < 0 || p.UpdateDelay < 0 || p.MaxRetries < 0 || p.MaxFailovers < 0 {
+			errs.add("updatePolicy", "step, updateDelay, maxRetries, maxFailovers should not be negative")
+		}
+		switch p.Action {
+		case "", "stop", "rollback":
+		default:
+			errs.add("updatePolicy.action", "should be one of [stop rollback]")
+		}
+		switch p.Strategy {
+		case "", "rolling":
+		case "canary":
+			if v.IsDaemon() {
+				errs.add("updatePolicy.strategy", "canary not allowed for daemon mode")
+			}
+			if c := p.Canary; c == nil {
+				errs.add("updatePolicy.canary", "required for canary strategy")
+			} else {
+				if c.Instances <= 0 {
+					errs.add("updatePolicy.canary.instances", "should be positive")
+				}
+				if c.SoakSeconds < 0 {
+					errs.add("updatePolicy.canary.soakSeconds", "should not be negative")
+				}
+			}
+		case "bluegreen":
+			if v.IsDaemon() {
+				errs.add("updatePolicy.strategy", "bluegreen not allowed for daemon mode")
+			}
+			if b := p.BlueGreen; b != nil && b.DrainSeconds < 0 {
+				errs.add("updatePolicy.blueGreen.drainSeconds", "should not be negative")
+			}
+		default:
+			errs.add("updatePolicy.strategy", "should be one of [rolling canary bluegreen]")
+		}
+	}
+
+	switch v.Kind {
+	case "", KindService:
+		if v.Job != nil {
+			errs.add("job", "only allowed for job kind")
+		}
+	case KindJob:
+		if v.Mode == "fixed" || v.Mode == "daemon" {
+			errs.add("mode", "%s mode not allowed for job kind", v.Mode)
+		}
+		if j := v.Job; j != nil && (j.Completions < 0 || j.Parallelism < 0 || j.BackoffLimit < 0 || j.ActiveDeadlineSeconds < 0) {
+			errs.add("job", "completions, parallelism, backoffLimit, activeDeadlineSeconds should not be negative")
+		}
+	default:
+		errs.add("kind", "should be one of [service job]")
+	}
+
+	if g := v.Gateway; g != nil && (g.Weight < 0 || g.Weight > 100) {
+		errs.add("gateway.weight", "should be in range [0, 100]")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (v *AppVersion) validContainer(errs *ValidationErrors) {
+	c := v.Container
+	if c == nil {
+		return
+	}
+
+	if strings.ToLower(c.Type) != "docker" {
+		errs.add("container.type", "only docker supported")
+	}
+
+	for i, vol := range c.Volumes {
+		field := fmt.Sprintf("container.volumes[%d]", i)
+		if !filepath.IsAbs(vol.ContainerPath) {
+			errs.add(field+".containerPath", "should be an absolute path")
+		}
+		if !filepath.IsAbs(vol.HostPath) {
+			errs.add(field+".hostPath", "should be an absolute path")
+		}
+		switch strings.ToUpper(vol.Mode) {
+		case "", "RW", "RO":
+		default:
+			errs.add(field+".mode", "should be one of [RW RO]")
+		}
+	}
+
+	d := c.Docker
+	if d == nil {
+		errs.add("container.docker", "required")
+		return
+	}
+
+	if d.Image == "" {
+		errs.add("container.docker.image", "required")
+	}
+
+	for i, p := range d.Parameters {
+		if p.Key == "" {
+			errs.add(fmt.Sprintf("container.docker.parameters[%d].key", i), "required")
+		}
+	}
+
+	var (
+		network   = strings.ToLower(d.Network)
+		names     = make(map[string]bool)
+		hostPorts = make(map[int32]bool)
+	)
+	for i, pm := range d.PortMappings {
+		field := fmt.Sprintf("container.docker.portMappings[%d]", i)
+
+		if network != "host" && (pm.ContainerPort <= 0 || pm.ContainerPort > 65535) {
+			errs.add(field+".containerPort", "should be in range [1, 65535]")
+		}
+		if pm.HostPort < 0 || pm.HostPort > 65535 {
+			errs.add(field+".hostPort", "should be in range [0, 65535]")
+		}
+		if pm.HostPort > 0 {
+			if hostPorts[pm.HostPort] {
+				errs.add(field+".hostPort", "duplicated host port %d", pm.HostPort)
+			}
+			hostPorts[pm.HostPort] = true
+		}
+
+		switch strings.ToLower(pm.Protocol) {
+		case "", "tcp", "udp":
+		default:
+			errs.add(field+".protocol", "should be one of [tcp udp]")
+		}
+
+		if pm.Name != "" {
+			if names[pm.Name] {
+				errs.add(field+".name", "duplicated port name %s", pm.Name)
+			}
+			names[pm.Name] = true
+		}
+	}
+
+	if network == "none" && len(d.PortMappings) > 0 {
+		errs.add("container.docker.portMappings", "not allowed with none network")
+	}
+}
+
+func (v *AppVersion) validHealthCheck(errs *ValidationErrors) {
+	hc := v.HealthCheck
+	if hc == nil {
+		return
+	}
+
+	switch strings.ToLower(hc.Protocol) {
+	case "http":
+		if !strings.HasPrefix(hc.Path, "/") {
+			errs.add("healthCheck.path", "should start with /")
+		}
+	case "tcp":
+	case "cmd":
+		if hc.Value == "" {
+			errs.add("healthCheck.value", "command required")
+		}
+	default:
+		errs.add("healthCheck.protocol", "should be one of [http tcp cmd]")
+	}
+
+	if p := strings.ToLower(hc.Protocol); (p == "http" || p == "tcp") && hc.Port <= 0 {
+		var pms []*PortMapping
+		if v.Container != nil && v.Container.Docker != nil {
+			pms = v.Container.Docker.PortMappings
+		}
+
+		if hc.PortName != "" {
+			var found bool
+			for _, pm := range pms {
+				if pm.Name == hc.PortName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				errs.add("healthCheck.portName", "no such port mapping %s", hc.PortName)
+			}
+		} else if hc.PortIndex < 0 || int(hc.PortIndex) >= len(pms) {
+			errs.add("healthCheck.portIndex", "no such port mapping index %d", hc.PortIndex)
+		}
+	}
+
+	if hc.IntervalSeconds < 0 || hc.TimeoutSeconds < 0 || hc.DelaySeconds < 0 || hc.GracePeriodSeconds < 0 {
+		errs.add("healthCheck", "intervalSeconds, timeoutSeconds, delaySeconds, gracePeriodSeconds should not be negative")
+	}
+	if hc.IntervalSeconds > 0 && hc.TimeoutSeconds > hc.IntervalSeconds {
+		errs.add("healthCheck.timeoutSeconds", "should not be greater than intervalSeconds")
+	}
+}